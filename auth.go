@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	missingTokenError       gRPCServerError = status.Error(codes.Unauthenticated, "bearer token is missing")
+	invalidTokenError       gRPCServerError = status.Error(codes.Unauthenticated, "bearer token is invalid")
+	invalidCertificateError gRPCServerError = status.Error(
+		codes.Unauthenticated,
+		"client certificate subject is unknown",
+	)
+	sportNotAllowedError    gRPCServerError = status.Error(codes.PermissionDenied, "sport is not allowed")
+	intervalNotAllowedError gRPCServerError = status.Error(
+		codes.PermissionDenied,
+		"interval is less than the minimum allowed one",
+	)
+)
+
+// unauthenticatedMethodPrefixes are served without a token, so that probes don't need credentials.
+var unauthenticatedMethodPrefixes = []string{"/grpc.health.v1.Health/"}
+
+// principal is an authenticated client together with what it is allowed to subscribe to.
+type principal struct {
+	name string
+	// nil allows every sport
+	sportNames  map[string]struct{}
+	minInterval time.Duration
+}
+
+func newPrincipal(name string, sportNames []string, minInterval string) (*principal, error) {
+	p := &principal{
+		name:        name,
+		sportNames:  nil,
+		minInterval: 0,
+	}
+
+	if len(sportNames) != 0 {
+		p.sportNames = make(map[string]struct{}, len(sportNames))
+		for _, sportName := range sportNames {
+			p.sportNames[sportName] = struct{}{}
+		}
+	}
+
+	if minInterval != "" {
+		interval, err := parseSecondsOrDuration(minInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid minimum interval of %s: %w", name, err)
+		}
+
+		p.minInterval = interval
+	}
+
+	return p, nil
+}
+
+func (p *principal) isAllowed(sportName string) bool {
+	_, exists := p.sportNames[sportName]
+
+	return p.sportNames == nil || exists
+}
+
+// authorize checks that the principal may receive every sport of the subscription as often as requested.
+func (p *principal) authorize(subscription subscription) error {
+	for sportName := range subscription.sportNames {
+		if !p.isAllowed(sportName) {
+			log.Infof("%s isn't allowed to subscribe to %s", p.name, sportName)
+
+			return sportNotAllowedError
+		}
+
+		if subscription.sportInterval(sportName) < p.minInterval {
+			log.Infof("%s isn't allowed to receive %s more often than every %s", p.name, sportName, p.minInterval)
+
+			return intervalNotAllowedError
+		}
+	}
+
+	return nil
+}
+
+type principalKey struct{}
+
+func withPrincipal(ctx context.Context, p *principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// principalFromContext returns nil if authentication is disabled.
+func principalFromContext(ctx context.Context) *principal {
+	p, _ := ctx.Value(principalKey{}).(*principal)
+
+	return p
+}
+
+type authenticator interface {
+	authenticate(token string) (*principal, error)
+	authenticateCertificate(cert *x509.Certificate) (*principal, error)
+}
+
+// staticKeys authenticates tokens and client certificate subjects listed in a keys file.
+type staticKeys struct {
+	tokenToPrincipal   map[string]*principal
+	subjectToPrincipal map[string]*principal
+}
+
+type staticKey struct {
+	Token       string   `json:"token"`
+	Subject     string   `json:"subject"`
+	Principal   string   `json:"principal"`
+	SportNames  []string `json:"sportNames"`
+	MinInterval string   `json:"minInterval"`
+}
+
+// loadStaticKeys reads a JSON list of keys with the principal, allowed sports and minimum interval
+// of every token or client certificate subject.
+func loadStaticKeys(path string) (*staticKeys, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []staticKey
+
+	err = json.Unmarshal(data, &keys)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse keys file: %w", err)
+	}
+
+	k := &staticKeys{
+		tokenToPrincipal:   make(map[string]*principal, len(keys)),
+		subjectToPrincipal: make(map[string]*principal),
+	}
+
+	for _, key := range keys {
+		if (key.Token == "") == (key.Subject == "") || key.Principal == "" {
+			return nil, fmt.Errorf("every key needs a principal and either a token or a subject")
+		}
+
+		p, err := newPrincipal(key.Principal, key.SportNames, key.MinInterval)
+		if err != nil {
+			return nil, err
+		}
+
+		if key.Token != "" {
+			k.tokenToPrincipal[key.Token] = p
+		} else {
+			k.subjectToPrincipal[key.Subject] = p
+		}
+	}
+
+	return k, nil
+}
+
+func (k *staticKeys) authenticate(token string) (*principal, error) {
+	p, exists := k.tokenToPrincipal[token]
+	if !exists {
+		return nil, invalidTokenError
+	}
+
+	return p, nil
+}
+
+func (k *staticKeys) authenticateCertificate(cert *x509.Certificate) (*principal, error) {
+	p, exists := k.subjectToPrincipal[cert.Subject.String()]
+	if !exists {
+		return nil, invalidCertificateError
+	}
+
+	return p, nil
+}
+
+// verifiedCertificates accepts every client certificate which passed TLS verification,
+// the subject is the principal and it isn't restricted.
+type verifiedCertificates struct{}
+
+func (verifiedCertificates) authenticate(string) (*principal, error) {
+	return nil, invalidTokenError
+}
+
+func (verifiedCertificates) authenticateCertificate(cert *x509.Certificate) (*principal, error) {
+	return newPrincipal(cert.Subject.String(), nil, "")
+}
+
+type principalClaims struct {
+	jwt.RegisteredClaims
+	SportNames  []string `json:"sports"`
+	MinInterval string   `json:"minInterval"`
+}
+
+// jwtVerifier authenticates JWTs signed with a local key, the subject is the principal.
+type jwtVerifier struct {
+	key     interface{}
+	methods []string
+}
+
+// loadJWTVerifier reads an RSA or ECDSA public key in PEM.
+// A file which isn't a public key is an error, HMAC secrets are loaded only by loadJWTSecret.
+func loadJWTVerifier(path string) (*jwtVerifier, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return &jwtVerifier{key: rsaKey, methods: []string{"RS256", "RS384", "RS512"}}, nil
+	}
+
+	if ecKey, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		return &jwtVerifier{key: ecKey, methods: []string{"ES256", "ES384", "ES512"}}, nil
+	}
+
+	// jwt parses only PKIX keys and certificates
+	if block, _ := pem.Decode(data); block != nil && block.Type == "RSA PUBLIC KEY" {
+		rsaKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse JWT key: %w", err)
+		}
+
+		return &jwtVerifier{key: rsaKey, methods: []string{"RS256", "RS384", "RS512"}}, nil
+	}
+
+	return nil, fmt.Errorf("JWT key file %s isn't an RSA or ECDSA public key in PEM", path)
+}
+
+// loadJWTSecret reads an HMAC secret, anyone who knows it can sign tokens.
+func loadJWTSecret(path string) (*jwtVerifier, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := bytes.TrimSpace(data)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("JWT secret file is empty")
+	}
+
+	if bytes.HasPrefix(secret, []byte("-----BEGIN")) {
+		return nil, fmt.Errorf("JWT secret file %s is a PEM file, public keys are set by --auth-jwt-key", path)
+	}
+
+	return &jwtVerifier{key: secret, methods: []string{"HS256", "HS384", "HS512"}}, nil
+}
+
+func (v *jwtVerifier) authenticate(token string) (*principal, error) {
+	claims := &principalClaims{}
+
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return v.key, nil
+	}, jwt.WithValidMethods(v.methods))
+	if err != nil || claims.Subject == "" {
+		return nil, invalidTokenError
+	}
+
+	p, err := newPrincipal(claims.Subject, claims.SportNames, claims.MinInterval)
+	if err != nil {
+		return nil, invalidTokenError
+	}
+
+	return p, nil
+}
+
+func (v *jwtVerifier) authenticateCertificate(*x509.Certificate) (*principal, error) {
+	return nil, invalidCertificateError
+}
+
+// authenticators tries every configured way of authentication in turn.
+type authenticators []authenticator
+
+func (a authenticators) authenticate(token string) (*principal, error) {
+	for _, auth := range a {
+		p, err := auth.authenticate(token)
+		if err == nil {
+			return p, nil
+		}
+	}
+
+	return nil, invalidTokenError
+}
+
+func (a authenticators) authenticateCertificate(cert *x509.Certificate) (*principal, error) {
+	for _, auth := range a {
+		p, err := auth.authenticateCertificate(cert)
+		if err == nil {
+			return p, nil
+		}
+	}
+
+	return nil, invalidCertificateError
+}
+
+// authenticateCredentials prefers the bearer token of the authorization header over the client certificate.
+func (a authenticators) authenticateCredentials(authorization string, cert *x509.Certificate) (*principal, error) {
+	switch {
+	case strings.HasPrefix(authorization, "Bearer "):
+		return a.authenticate(strings.TrimPrefix(authorization, "Bearer "))
+	case cert != nil:
+		return a.authenticateCertificate(cert)
+	}
+
+	return nil, missingTokenError
+}
+
+// authenticateHTTP returns a nil principal if authentication is disabled.
+// The token is a bearer token sent without the authorization header, as browsers do for EventSource and WebSocket.
+// The header is preferred if the request has both.
+func (a authenticators) authenticateHTTP(r *http.Request, token string) (*principal, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" && token != "" {
+		authorization = "Bearer " + token
+	}
+
+	var cert *x509.Certificate
+	if r.TLS != nil && len(r.TLS.VerifiedChains) != 0 && len(r.TLS.VerifiedChains[0]) != 0 {
+		cert = r.TLS.VerifiedChains[0][0]
+	}
+
+	p, err := a.authenticateCredentials(authorization, cert)
+	if err != nil {
+		log.Infof("rejected %s: %v", r.URL.Path, err)
+
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (a authenticators) authenticateContext(ctx context.Context, method string) (context.Context, error) {
+	for _, prefix := range unauthenticatedMethodPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return ctx, nil
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	authorization := ""
+
+	if values := md.Get("authorization"); len(values) != 0 {
+		authorization = values[0]
+	}
+
+	p, err := a.authenticateCredentials(authorization, verifiedClientCertificate(ctx))
+	if err != nil {
+		log.Infof("rejected %s: %v", method, err)
+
+		return nil, err
+	}
+
+	if details := callDetailsFromContext(ctx); details != nil {
+		details.principal = p.name
+	}
+
+	return withPrincipal(ctx, p), nil
+}
+
+func (a authenticators) unaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	ctx, err := a.authenticateContext(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (a authenticators) streamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, err := a.authenticateContext(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+}
+
+// verifiedClientCertificate returns the leaf certificate of the client if TLS verified it.
+func verifiedClientCertificate(ctx context.Context) *x509.Certificate {
+	p, exists := peer.FromContext(ctx)
+	if !exists {
+		return nil
+	}
+
+	tlsInfo, isTLS := p.AuthInfo.(credentials.TLSInfo)
+	if !isTLS || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	return tlsInfo.State.VerifiedChains[0][0]
+}
+
+// contextServerStream replaces the context of a stream.
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// authOptions returns the interceptors of the configured authenticators, no options disable authentication.
+func authOptions(a authenticators) []grpc.ServerOption {
+	if len(a) == 0 {
+		return nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(a.unaryInterceptor),
+		grpc.ChainStreamInterceptor(a.streamInterceptor),
+	}
+}
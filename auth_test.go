@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func initAuthenticatedClient(t *testing.T, serverAddr, token string) SportLinesService_SubscribeOnSportLinesClient {
+	conn, err := grpc.Dial(serverAddr, grpc.WithInsecure())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	if token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+
+	stream, err := NewSportLinesServiceClient(conn).SubscribeOnSportLines(ctx)
+	require.NoError(t, err)
+
+	return stream
+}
+
+func TestLoadStaticKeys(t *testing.T) {
+	keys, err := loadStaticKeys(writeTestFile(t, "keys.json", `[
+		{"token": "alice-token", "principal": "alice", "sportNames": ["soccer"], "minInterval": "2s"},
+		{"token": "bob-token", "principal": "bob"}
+	]`))
+	require.NoError(t, err)
+
+	alice, err := keys.authenticate("alice-token")
+	require.NoError(t, err)
+	require.Equal(t, "alice", alice.name)
+	require.Equal(t, map[string]struct{}{soccerSport: {}}, alice.sportNames)
+	require.Equal(t, 2*time.Second, alice.minInterval)
+
+	bob, err := keys.authenticate("bob-token")
+	require.NoError(t, err)
+	require.Nil(t, bob.sportNames)
+
+	_, err = keys.authenticate("eve-token")
+	require.Equal(t, invalidTokenError, err)
+
+	_, err = loadStaticKeys(writeTestFile(t, "keys.json", `[{"token": "alice-token"}]`))
+	require.Error(t, err)
+}
+
+func TestJWTVerifier(t *testing.T) {
+	verifier, err := loadJWTSecret(writeTestFile(t, "secret", "top secret\n"))
+	require.NoError(t, err)
+
+	sign := func(claims jwt.Claims, secret string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		require.NoError(t, err)
+
+		return token
+	}
+
+	p, err := verifier.authenticate(sign(&principalClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alice",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		SportNames:  []string{soccerSport},
+		MinInterval: "1s",
+	}, "top secret"))
+	require.NoError(t, err)
+	require.Equal(t, "alice", p.name)
+	require.Equal(t, time.Second, p.minInterval)
+
+	_, err = verifier.authenticate(sign(&principalClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "alice"},
+	}, "wrong secret"))
+	require.Equal(t, invalidTokenError, err)
+
+	_, err = verifier.authenticate(sign(&principalClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alice",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+		},
+	}, "top secret"))
+	require.Equal(t, invalidTokenError, err)
+}
+
+func TestJWTVerifier_PKCS1PublicKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PUBLIC KEY",
+		Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey),
+	})
+
+	verifier, err := loadJWTVerifier(writeTestFile(t, "key.pem", string(publicKeyPEM)))
+	require.NoError(t, err)
+
+	claims := &principalClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "alice"}}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+	require.NoError(t, err)
+
+	p, err := verifier.authenticate(token)
+	require.NoError(t, err)
+	require.Equal(t, "alice", p.name)
+
+	// the public key is known to everyone, so it must never work as an HMAC secret
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(publicKeyPEM)
+	require.NoError(t, err)
+
+	_, err = verifier.authenticate(forged)
+	require.Equal(t, invalidTokenError, err)
+
+	_, err = loadJWTVerifier(writeTestFile(t, "key.pem", "-----BEGIN PUBLIC KEY-----\nbroken\n-----END PUBLIC KEY-----\n"))
+	require.Error(t, err)
+
+	_, err = loadJWTVerifier(writeTestFile(t, "secret", "top secret\n"))
+	require.Error(t, err)
+
+	_, err = loadJWTSecret(writeTestFile(t, "key.pem", string(publicKeyPEM)))
+	require.Error(t, err)
+}
+
+func TestPrincipal_Authorize(t *testing.T) {
+	p, err := newPrincipal("alice", []string{soccerSport, footballSport}, "2s")
+	require.NoError(t, err)
+
+	allowed := subscription{
+		sportNames:          map[string]struct{}{soccerSport: {}, footballSport: {}},
+		interval:            2 * time.Second,
+		sportNameToInterval: map[string]time.Duration{footballSport: 3 * time.Second},
+	}
+	require.NoError(t, p.authorize(allowed))
+
+	allowed.sportNameToInterval[footballSport] = time.Second
+	require.Equal(t, intervalNotAllowedError, p.authorize(allowed))
+
+	require.Equal(t, sportNotAllowedError, p.authorize(subscription{
+		sportNames: map[string]struct{}{baseballSport: {}},
+		interval:   time.Hour,
+	}))
+}
+
+func TestGRPCServer_Authentication(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	storage.Upload(context.Background(), footballSport, 0.5, time.Now())
+
+	keys, err := loadStaticKeys(writeTestFile(t, "keys.json", `[
+		{"token": "alice-token", "principal": "alice", "sportNames": ["soccer"], "minInterval": "2s"}
+	]`))
+	require.NoError(t, err)
+
+	serverAddr := initServerWith(t, newSportLinesPublisherServer(
+		storage,
+		nil,
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	), authOptions(authenticators{keys})...)
+
+	req := &SportLinesRequest{
+		SportNames:   []string{soccerSport},
+		TimeInterval: 2,
+	}
+
+	for _, token := range []string{"", "eve-token"} {
+		stream := initAuthenticatedClient(t, serverAddr, token)
+		_ = stream.Send(req)
+		_, err := stream.Recv()
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+
+	stream := initAuthenticatedClient(t, serverAddr, "alice-token")
+	require.NoError(t, stream.Send(req))
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, SportLinesResponse_SNAPSHOT, resp.Kind)
+
+	require.NoError(t, stream.Send(&SportLinesRequest{
+		SportNames: []string{footballSport},
+		Action:     SportLinesRequest_ADD,
+	}))
+
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, SportLinesResponse_ERROR, resp.Kind)
+	require.Equal(t, int32(codes.PermissionDenied), resp.Error.Code)
+
+	stream = initAuthenticatedClient(t, serverAddr, "alice-token")
+	require.NoError(t, stream.Send(&SportLinesRequest{
+		SportNames:   []string{soccerSport},
+		TimeInterval: 1,
+	}))
+
+	_, err = stream.Recv()
+	require.Equal(t, intervalNotAllowedError.Error(), err.Error())
+}
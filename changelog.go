@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const defaultReplayBufferSize = 1000
+
+type lineChange struct {
+	sequence uint64
+	line     float64
+}
+
+// lineChangeLog keeps the last changes of every sport seen by the publisher,
+// so that a client which lost its stream can get only the changes it missed.
+type lineChangeLog struct {
+	sync.Mutex
+	capacity           int
+	sequence           uint64
+	sportNameToChanges map[string][]lineChange
+}
+
+func newLineChangeLog(capacity int) *lineChangeLog {
+	return &lineChangeLog{
+		Mutex:    sync.Mutex{},
+		capacity: capacity,
+		// sequences of a previous run are always older than the ones of the current run,
+		// so resuming with them leads to a gap instead of wrong deltas
+		sequence:           uint64(time.Now().UnixNano()),
+		sportNameToChanges: make(map[string][]lineChange),
+	}
+}
+
+// observe reads current lines from the storage, records the changed ones
+// and returns them together with their pull times and the sequence they correspond to.
+// Sports which weren't pulled yet are left out.
+func (l *lineChangeLog) observe(
+	ctx context.Context,
+	storage storage,
+	sportNames map[string]struct{},
+) (map[string]float64, map[string]time.Time, uint64) {
+	l.Lock()
+	defer l.Unlock()
+
+	lines := make(map[string]float64, len(sportNames))
+	pullTimes := make(map[string]time.Time, len(sportNames))
+
+	for sportName := range sportNames {
+		line, pulledAt, exists := storage.Get(ctx, sportName)
+		if exists {
+			lines[sportName] = line
+			pullTimes[sportName] = pulledAt
+			l.record(sportName, line)
+		}
+	}
+
+	return lines, pullTimes, l.sequence
+}
+
+// linesAt returns lines as they were at the given sequence.
+// If the lines at this sequence are not in the buffer anymore, false is returned.
+func (l *lineChangeLog) linesAt(sportNames map[string]struct{}, sequence uint64) (map[string]float64, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	if sequence > l.sequence {
+		return nil, false
+	}
+
+	lines := make(map[string]float64, len(sportNames))
+
+	for sportName := range sportNames {
+		line, exists := l.lineAt(sportName, sequence)
+		if !exists {
+			return nil, false
+		}
+
+		lines[sportName] = line
+	}
+
+	return lines, true
+}
+
+func (l *lineChangeLog) record(sportName string, line float64) {
+	changes := l.sportNameToChanges[sportName]
+	if len(changes) != 0 && changes[len(changes)-1].line == line {
+		return
+	}
+
+	if len(changes) == l.capacity {
+		changes = changes[1:]
+	}
+
+	l.sequence++
+	l.sportNameToChanges[sportName] = append(changes, lineChange{
+		sequence: l.sequence,
+		line:     line,
+	})
+}
+
+func (l *lineChangeLog) lineAt(sportName string, sequence uint64) (float64, bool) {
+	changes := l.sportNameToChanges[sportName]
+
+	for i := len(changes) - 1; i >= 0; i-- {
+		if changes[i].sequence <= sequence {
+			return changes[i].line, true
+		}
+	}
+
+	return 0, false
+}
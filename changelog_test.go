@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineChangeLog_Observe(t *testing.T) {
+	s := newMapStorage()
+	l := newLineChangeLog(defaultReplayBufferSize)
+	sportNames := map[string]struct{}{"football": {}}
+
+	s.Upload(context.Background(), "football", 0.1, time.Now())
+	lines, _, sequence := l.observe(context.Background(), s, sportNames)
+	require.Equal(t, map[string]float64{"football": 0.1}, lines)
+
+	_, _, sameSequence := l.observe(context.Background(), s, sportNames)
+	require.Equal(t, sequence, sameSequence)
+
+	s.Upload(context.Background(), "football", 0.2, time.Now())
+	lines, _, nextSequence := l.observe(context.Background(), s, sportNames)
+	require.Equal(t, map[string]float64{"football": 0.2}, lines)
+	require.Equal(t, sequence+1, nextSequence)
+}
+
+func TestLineChangeLog_LinesAt(t *testing.T) {
+	s := newMapStorage()
+	l := newLineChangeLog(defaultReplayBufferSize)
+	sportNames := map[string]struct{}{"football": {}, "soccer": {}}
+
+	s.Upload(context.Background(), "football", 0.1, time.Now())
+	s.Upload(context.Background(), "soccer", 0.2, time.Now())
+	_, _, sequence := l.observe(context.Background(), s, sportNames)
+
+	s.Upload(context.Background(), "football", 0.3, time.Now())
+	s.Upload(context.Background(), "soccer", 0.4, time.Now())
+	lines, _, _ := l.observe(context.Background(), s, sportNames)
+	require.Equal(t, map[string]float64{"football": 0.3, "soccer": 0.4}, lines)
+
+	prevLines, exists := l.linesAt(sportNames, sequence)
+	require.True(t, exists)
+	require.Equal(t, map[string]float64{"football": 0.1, "soccer": 0.2}, prevLines)
+}
+
+func TestLineChangeLog_Overflow(t *testing.T) {
+	s := newMapStorage()
+	l := newLineChangeLog(2)
+	sportNames := map[string]struct{}{"football": {}}
+
+	s.Upload(context.Background(), "football", 0.1, time.Now())
+	_, _, sequence := l.observe(context.Background(), s, sportNames)
+
+	s.Upload(context.Background(), "football", 0.2, time.Now())
+	l.observe(context.Background(), s, sportNames)
+
+	_, exists := l.linesAt(sportNames, sequence)
+	require.True(t, exists)
+
+	s.Upload(context.Background(), "football", 0.3, time.Now())
+	l.observe(context.Background(), s, sportNames)
+
+	_, exists = l.linesAt(sportNames, sequence)
+	require.False(t, exists)
+}
+
+func TestLineChangeLog_UnknownSequence(t *testing.T) {
+	s := newMapStorage()
+	l := newLineChangeLog(defaultReplayBufferSize)
+	sportNames := map[string]struct{}{"football": {}}
+
+	s.Upload(context.Background(), "football", 0.1, time.Now())
+	_, _, sequence := l.observe(context.Background(), s, sportNames)
+
+	_, exists := l.linesAt(sportNames, sequence+1)
+	require.False(t, exists)
+
+	_, exists = l.linesAt(sportNames, 1)
+	require.False(t, exists)
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const cliHelp = "commands: sports a,b | add a,b | remove a,b | interval 2s | resync | quit"
+
+var errQuit = errors.New("quit")
+
+// runSubscribeCommand subscribes to sport lines and renders them as a live table
+// while the operator changes the subscription from stdin.
+func runSubscribeCommand(args []string) error {
+	flags := flag.NewFlagSet("subscribe", flag.ExitOnError)
+	grpcAddr := flags.String("grpc", "localhost:8091", "address of grpc server")
+	sportNames := flags.String("sports", "baseball,football,soccer", "comma separated sports to subscribe to")
+	interval := secondsOrDuration(time.Second)
+	flags.Var(&interval, "interval", "interval of receiving lines (seconds or duration like 500ms)")
+	tlsCAPath := flags.String("tls-ca", "", "PEM CA which the server certificate must be signed by, enables TLS")
+	tlsCertPath := flags.String("tls-cert", "", "PEM client certificate for mutual TLS, enables TLS")
+	tlsKeyPath := flags.String("tls-key", "", "PEM private key of the client certificate")
+	token := flags.String("token", "", "bearer token sent in the authorization metadata")
+
+	_ = flags.Parse(args)
+
+	credentialsOption, err := cliTransportCredentials(*tlsCAPath, *tlsCertPath, *tlsKeyPath)
+	if err != nil {
+		return err
+	}
+
+	conn, err := grpc.Dial(*grpcAddr, credentialsOption)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	if *token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+*token)
+	}
+
+	stream, err := NewSportLinesServiceClient(conn).SubscribeOnSportLines(ctx)
+	if err != nil {
+		return err
+	}
+
+	sender := &lockedSender{
+		Mutex:  sync.Mutex{},
+		stream: stream,
+	}
+	commands := newCommandParser()
+
+	err = sender.send(commands.change(subscriptionChange{
+		sportNames: splitCommaSeparated(*sportNames),
+		interval:   time.Duration(interval),
+	}))
+	if err != nil {
+		return err
+	}
+
+	table := newLineTable()
+
+	go readCommands(os.Stdin, sender, commands, table)
+
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		closeRequests, isStale := commands.reply(resp)
+		for _, req := range closeRequests {
+			_ = sender.send(req)
+		}
+
+		if !isStale {
+			table.apply(resp)
+			table.render(os.Stdout)
+		}
+
+		if resp.Kind == SportLinesResponse_RECONNECT {
+			return nil
+		}
+	}
+}
+
+// cliTransportCredentials dials without TLS unless a CA or a client certificate is given,
+// the server certificate is checked against the system roots if there is no CA.
+func cliTransportCredentials(caPath, certPath, keyPath string) (grpc.DialOption, error) {
+	if caPath == "" && certPath == "" && keyPath == "" {
+		return grpc.WithInsecure(), nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caPath != "" {
+		roots, err := loadCertPool(caPath)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = roots
+	}
+
+	if certPath != "" || keyPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return grpc.WithTransportCredentials(credentials.NewTLS(config)), nil
+}
+
+// lockedSender lets the command reader and the response loop send requests on the same stream.
+type lockedSender struct {
+	sync.Mutex
+	stream SportLinesService_SubscribeOnSportLinesClient
+}
+
+func (s *lockedSender) send(req *SportLinesRequest) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.stream.Send(req)
+}
+
+func (s *lockedSender) closeSend() {
+	s.Lock()
+	defer s.Unlock()
+
+	_ = s.stream.CloseSend()
+}
+
+func readCommands(r io.Reader, sender *lockedSender, commands *commandParser, table *lineTable) {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		req, err := commands.parse(scanner.Text())
+		if errors.Is(err, errQuit) {
+			break
+		}
+
+		if err != nil {
+			table.setMessage(err.Error())
+
+			continue
+		}
+
+		err = sender.send(req)
+		if err != nil {
+			return
+		}
+	}
+
+	sender.closeSend()
+}
+
+func splitCommaSeparated(list string) []string {
+	var items []string
+
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// subscriptionChange is the subscription the operator has asked for.
+type subscriptionChange struct {
+	groupID    string
+	sportNames []string
+	interval   time.Duration
+}
+
+// commandParser turns operator commands into requests. Every change of the subscription is sent as a new group,
+// so that the response for that group tells whether the server has accepted it. The change is applied only
+// once the group gets its snapshot, then the previous group is closed. A rejected change is dropped.
+type commandParser struct {
+	sync.Mutex
+	// the accepted subscription, its group is the one shown in the table
+	current subscriptionChange
+	// sent changes in the order of their requests, the last one is the base of the next command
+	pending         []subscriptionChange
+	lastGroupNumber int
+}
+
+func newCommandParser() *commandParser {
+	return &commandParser{
+		Mutex:           sync.Mutex{},
+		current:         subscriptionChange{groupID: "", sportNames: nil, interval: 0},
+		pending:         nil,
+		lastGroupNumber: 0,
+	}
+}
+
+// change sends the subscription as a new group and keeps it pending until the server replies.
+func (p *commandParser) change(change subscriptionChange) *SportLinesRequest {
+	p.Lock()
+	defer p.Unlock()
+
+	p.lastGroupNumber++
+	change.groupID = "cli-" + strconv.Itoa(p.lastGroupNumber)
+	p.pending = append(p.pending, change)
+
+	return &SportLinesRequest{
+		SportNames: change.sportNames,
+		Interval:   durationpb.New(change.interval),
+		GroupId:    change.groupID,
+	}
+}
+
+// latest returns what the operator has asked for last, whether or not the server has accepted it yet.
+func (p *commandParser) latest() subscriptionChange {
+	p.Lock()
+	defer p.Unlock()
+
+	if len(p.pending) != 0 {
+		return p.pending[len(p.pending)-1]
+	}
+
+	return p.current
+}
+
+func (p *commandParser) currentGroupID() string {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.current.groupID
+}
+
+// reply applies or drops the pending change the response is for and returns the requests closing the groups
+// it replaces. The response is stale if it has lines of a group which isn't shown.
+func (p *commandParser) reply(resp *SportLinesResponse) ([]*SportLinesRequest, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	for i, change := range p.pending {
+		if change.groupID != resp.GroupId {
+			continue
+		}
+
+		switch resp.Kind {
+		case SportLinesResponse_ERROR:
+			p.pending = append(p.pending[:i], p.pending[i+1:]...)
+
+			return nil, false
+		case SportLinesResponse_SNAPSHOT, SportLinesResponse_GAP_SNAPSHOT, SportLinesResponse_DELTA:
+		default:
+			return nil, false
+		}
+
+		// replies come in the order of requests, so the earlier groups which got none are closed as well
+		closeRequests := make([]*SportLinesRequest, 0, i+1)
+		for _, replaced := range append([]subscriptionChange{p.current}, p.pending[:i]...) {
+			if replaced.groupID != "" {
+				closeRequests = append(closeRequests, &SportLinesRequest{
+					Action:  SportLinesRequest_CLOSE,
+					GroupId: replaced.groupID,
+				})
+			}
+		}
+
+		p.current = change
+		p.pending = p.pending[i+1:]
+
+		return closeRequests, false
+	}
+
+	switch resp.Kind {
+	case SportLinesResponse_SNAPSHOT, SportLinesResponse_GAP_SNAPSHOT, SportLinesResponse_DELTA:
+		return nil, resp.GroupId != p.current.groupID
+	}
+
+	return nil, false
+}
+
+func (p *commandParser) parse(line string) (*SportLinesRequest, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, errors.New(cliHelp)
+	}
+
+	command, argument := fields[0], strings.Join(fields[1:], "")
+	change := p.latest()
+
+	switch command {
+	case "quit", "exit":
+		return nil, errQuit
+	case "resync":
+		return &SportLinesRequest{Action: SportLinesRequest_RESYNC, GroupId: p.currentGroupID()}, nil
+	case "interval":
+		interval, err := parseSecondsOrDuration(argument)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q: %w", argument, err)
+		}
+
+		change.interval = interval
+
+		return p.change(change), nil
+	case "sports", "add", "remove":
+	default:
+		return nil, fmt.Errorf("unknown command %q, %s", command, cliHelp)
+	}
+
+	sportNames := splitCommaSeparated(argument)
+	if len(sportNames) == 0 {
+		return nil, fmt.Errorf("%s needs comma separated sports", command)
+	}
+
+	switch command {
+	case "add":
+		change.sportNames = append(append([]string(nil), change.sportNames...), sportNames...)
+	case "remove":
+		change.sportNames = subtractSportNames(change.sportNames, sportNames)
+	default:
+		change.sportNames = sportNames
+	}
+
+	return p.change(change), nil
+}
+
+func subtractSportNames(sportNames, removed []string) []string {
+	var left []string
+
+	for _, sportName := range sportNames {
+		isRemoved := false
+
+		for _, removedSportName := range removed {
+			if sportName == removedSportName {
+				isRemoved = true
+			}
+		}
+
+		if !isRemoved {
+			left = append(left, sportName)
+		}
+	}
+
+	return left
+}
+
+// lineTable reconstructs absolute lines from the responses of a stream.
+type lineTable struct {
+	sync.Mutex
+	lines    map[string]float64
+	pending  []string
+	sequence uint64
+	message  string
+}
+
+func newLineTable() *lineTable {
+	return &lineTable{
+		Mutex:    sync.Mutex{},
+		lines:    make(map[string]float64),
+		pending:  nil,
+		sequence: 0,
+		message:  "",
+	}
+}
+
+func (t *lineTable) apply(resp *SportLinesResponse) {
+	t.Lock()
+	defer t.Unlock()
+
+	switch resp.Kind {
+	case SportLinesResponse_HEARTBEAT:
+		return
+	case SportLinesResponse_ERROR:
+		t.message = "request rejected: " + resp.Error.GetMessage()
+
+		return
+	case SportLinesResponse_RECONNECT:
+		t.message = "server asked to reconnect"
+
+		return
+	case SportLinesResponse_SNAPSHOT, SportLinesResponse_GAP_SNAPSHOT:
+		t.lines = make(map[string]float64, len(resp.SportNameToLine))
+	case SportLinesResponse_DELTA:
+		for _, sportName := range resp.AbsoluteSportNames {
+			t.lines[sportName] = 0
+		}
+	}
+
+	for sportName, line := range resp.SportNameToLine {
+		t.lines[sportName] += line
+	}
+
+	t.pending = resp.PendingSportNames
+	t.sequence = resp.Sequence
+}
+
+func (t *lineTable) setMessage(message string) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.message = message
+}
+
+func (t *lineTable) render(w io.Writer) {
+	t.Lock()
+	defer t.Unlock()
+
+	sportNames := make([]string, 0, len(t.lines))
+	for sportName := range t.lines {
+		sportNames = append(sportNames, sportName)
+	}
+
+	sort.Strings(sportNames)
+
+	// moves the cursor home and clears the screen
+	fmt.Fprint(w, "\033[H\033[2J")
+	fmt.Fprintf(w, "sequence: %d\n\n", t.sequence)
+	fmt.Fprintf(w, "%-12s %10s\n", "SPORT", "LINE")
+
+	for _, sportName := range sportNames {
+		fmt.Fprintf(w, "%-12s %10.3f\n", sportName, t.lines[sportName])
+	}
+
+	for _, sportName := range t.pending {
+		fmt.Fprintf(w, "%-12s %10s\n", sportName, "pending")
+	}
+
+	fmt.Fprintf(w, "\n%s\n%s\n", t.message, cliHelp)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineTable_Apply(t *testing.T) {
+	table := newLineTable()
+
+	table.apply(&SportLinesResponse{
+		SportNameToLine:   map[string]float64{soccerSport: 0.5},
+		Sequence:          1,
+		Kind:              SportLinesResponse_SNAPSHOT,
+		PendingSportNames: []string{footballSport},
+	})
+	table.apply(&SportLinesResponse{
+		SportNameToLine: map[string]float64{soccerSport: 0.25, footballSport: 0.75},
+		Sequence:        2,
+		Kind:            SportLinesResponse_DELTA,
+	})
+	table.apply(&SportLinesResponse{Kind: SportLinesResponse_HEARTBEAT})
+	require.Equal(t, map[string]float64{soccerSport: 0.75, footballSport: 0.75}, table.lines)
+	require.Equal(t, uint64(2), table.sequence)
+
+	table.apply(&SportLinesResponse{
+		SportNameToLine:    map[string]float64{soccerSport: 0.25, baseballSport: 0.3},
+		Sequence:           3,
+		Kind:               SportLinesResponse_DELTA,
+		AbsoluteSportNames: []string{baseballSport},
+	})
+	require.Equal(t, map[string]float64{soccerSport: 1, footballSport: 0.75, baseballSport: 0.3}, table.lines)
+
+	table.apply(&SportLinesResponse{
+		SportNameToLine: map[string]float64{soccerSport: 0.1},
+		Sequence:        4,
+		Kind:            SportLinesResponse_GAP_SNAPSHOT,
+	})
+	require.Equal(t, map[string]float64{soccerSport: 0.1}, table.lines)
+
+	out := &bytes.Buffer{}
+	table.render(out)
+	require.Contains(t, out.String(), "sequence: 4")
+	require.Contains(t, out.String(), "0.100")
+}
+
+func TestCommandParser_Parse(t *testing.T) {
+	parser := newCommandParser()
+	parser.change(subscriptionChange{groupID: "", sportNames: []string{soccerSport}, interval: time.Second})
+
+	req, err := parser.parse("add football, baseball")
+	require.NoError(t, err)
+	require.Equal(t, SportLinesRequest_REPLACE, req.Action)
+	require.Equal(t, "cli-2", req.GroupId)
+	require.Equal(t, []string{soccerSport, footballSport, baseballSport}, req.SportNames)
+
+	// commands build on the ones which aren't accepted yet
+	req, err = parser.parse("remove soccer")
+	require.NoError(t, err)
+	require.Equal(t, []string{footballSport, baseballSport}, req.SportNames)
+
+	req, err = parser.parse("interval 500ms")
+	require.NoError(t, err)
+	require.Equal(t, []string{footballSport, baseballSport}, req.SportNames)
+	require.Equal(t, 500*time.Millisecond, req.Interval.AsDuration())
+
+	req, err = parser.parse("sports soccer")
+	require.NoError(t, err)
+	require.Equal(t, []string{soccerSport}, req.SportNames)
+	require.Equal(t, 500*time.Millisecond, req.Interval.AsDuration())
+
+	req, err = parser.parse("resync")
+	require.NoError(t, err)
+	require.Equal(t, SportLinesRequest_RESYNC, req.Action)
+
+	_, err = parser.parse("interval -1")
+	require.Error(t, err)
+
+	_, err = parser.parse("add")
+	require.Error(t, err)
+
+	_, err = parser.parse("unsubscribe")
+	require.Error(t, err)
+
+	_, err = parser.parse("quit")
+	require.Equal(t, errQuit, err)
+}
+
+func TestCommandParser_Reply(t *testing.T) {
+	parser := newCommandParser()
+	first := parser.change(subscriptionChange{groupID: "", sportNames: []string{soccerSport}, interval: time.Second})
+
+	closeRequests, isStale := parser.reply(&SportLinesResponse{Kind: SportLinesResponse_SNAPSHOT, GroupId: first.GroupId})
+	require.Empty(t, closeRequests)
+	require.False(t, isStale)
+	require.Equal(t, first.GroupId, parser.current.groupID)
+
+	// a rejected change is dropped and the next command builds on the accepted subscription
+	rejected, err := parser.parse("add tennis")
+	require.NoError(t, err)
+
+	closeRequests, isStale = parser.reply(&SportLinesResponse{Kind: SportLinesResponse_ERROR, GroupId: rejected.GroupId})
+	require.Empty(t, closeRequests)
+	require.False(t, isStale)
+	require.Equal(t, []string{soccerSport}, parser.current.sportNames)
+
+	accepted, err := parser.parse("add football")
+	require.NoError(t, err)
+	require.Equal(t, []string{soccerSport, footballSport}, accepted.SportNames)
+
+	// the current group is shown until the change is accepted
+	_, isStale = parser.reply(&SportLinesResponse{Kind: SportLinesResponse_DELTA, GroupId: first.GroupId})
+	require.False(t, isStale)
+
+	closeRequests, isStale = parser.reply(&SportLinesResponse{
+		Kind:    SportLinesResponse_SNAPSHOT,
+		GroupId: accepted.GroupId,
+	})
+	require.False(t, isStale)
+	require.Equal(t, []*SportLinesRequest{{Action: SportLinesRequest_CLOSE, GroupId: first.GroupId}}, closeRequests)
+	require.Equal(t, []string{soccerSport, footballSport}, parser.current.sportNames)
+	require.Empty(t, parser.pending)
+
+	// lines of the replaced group sent before it was closed aren't shown
+	_, isStale = parser.reply(&SportLinesResponse{Kind: SportLinesResponse_DELTA, GroupId: first.GroupId})
+	require.True(t, isStale)
+}
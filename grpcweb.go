@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+)
+
+// grpcWebRequestHeaders are sent by gRPC-Web clients, authorization carries bearer tokens.
+var grpcWebRequestHeaders = []string{"authorization", "content-type", "grpc-timeout", "x-grpc-web", "x-user-agent"}
+
+// grpcWebHandler serves gRPC-Web calls and their CORS pre-flight requests with the gRPC server
+// and passes everything else to the HTTP API.
+type grpcWebHandler struct {
+	grpcWeb *grpcweb.WrappedGrpcServer
+	next    http.Handler
+}
+
+// newGRPCWebHandler allows the given origins to call the gRPC server from browsers, "*" allows any origin.
+func newGRPCWebHandler(s *grpc.Server, allowedOrigins []string, next http.Handler) *grpcWebHandler {
+	return &grpcWebHandler{
+		grpcWeb: grpcweb.WrapServer(
+			s,
+			grpcweb.WithOriginFunc(isAllowedOrigin(allowedOrigins)),
+			grpcweb.WithAllowedRequestHeaders(grpcWebRequestHeaders),
+			grpcweb.WithCorsForRegisteredEndpointsOnly(true),
+		),
+		next: next,
+	}
+}
+
+func (h *grpcWebHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.grpcWeb.IsGrpcWebRequest(r) || h.grpcWeb.IsAcceptableGrpcCorsRequest(r) {
+		h.grpcWeb.ServeHTTP(w, r.WithContext(withTransport(r.Context(), grpcWebTransport)))
+
+		return
+	}
+
+	h.next.ServeHTTP(w, r)
+}
+
+func isAllowedOrigin(allowedOrigins []string) func(origin string) bool {
+	return func(origin string) bool {
+		for _, allowedOrigin := range allowedOrigins {
+			if allowedOrigin == "*" || allowedOrigin == origin {
+				return true
+			}
+		}
+
+		return false
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/require"
+)
+
+const dashboardOrigin = "https://dashboard.example"
+
+func initGRPCWebServer(t *testing.T, storage storage) *httptest.Server {
+	s := newGRPCServer()
+	RegisterSportLinesServiceServer(s, newSportLinesPublisherServer(
+		storage,
+		nil,
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	))
+
+	server := httptest.NewServer(newGRPCWebHandler(s, []string{dashboardOrigin}, http.NotFoundHandler()))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGRPCWebHandler_ServerStream(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	server := initGRPCWebServer(t, storage)
+
+	data, err := proto.Marshal(&SportLinesRequest{SportNames: []string{soccerSport}, TimeInterval: 1})
+	require.NoError(t, err)
+
+	// a gRPC-Web message is a flag byte and a big-endian length followed by the message
+	body := &bytes.Buffer{}
+	body.WriteByte(0)
+	require.NoError(t, binary.Write(body, binary.BigEndian, uint32(len(data))))
+	body.Write(data)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		server.URL+"/protobuf.SportLinesService/subscribeOnSportLinesServerStream",
+		body,
+	)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("Origin", dashboardOrigin)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, dashboardOrigin, resp.Header.Get("Access-Control-Allow-Origin"))
+
+	header := make([]byte, 5)
+	_, err = io.ReadFull(resp.Body, header)
+	require.NoError(t, err)
+	require.Equal(t, byte(0), header[0])
+
+	data = make([]byte, binary.BigEndian.Uint32(header[1:]))
+	_, err = io.ReadFull(resp.Body, data)
+	require.NoError(t, err)
+
+	snapshot := &SportLinesResponse{}
+	require.NoError(t, proto.Unmarshal(data, snapshot))
+	require.Equal(t, SportLinesResponse_SNAPSHOT, snapshot.Kind)
+	require.Equal(t, map[string]float64{soccerSport: 0.5}, snapshot.SportNameToLine)
+
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		promhttp.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		return strings.Contains(w.Body.String(), `sportlines_delivery_latency_seconds_count{class="grpc-web",sport="soccer"}`)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestGRPCWebHandler_CORS(t *testing.T) {
+	server := initGRPCWebServer(t, newMapStorage())
+
+	preflight := func(origin, path string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "x-grpc-web,content-type,authorization")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		return resp
+	}
+
+	resp := preflight(dashboardOrigin, "/protobuf.SportLinesService/subscribeOnSportLinesServerStream")
+	require.Equal(t, dashboardOrigin, resp.Header.Get("Access-Control-Allow-Origin"))
+	require.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "Authorization")
+
+	resp = preflight("https://elsewhere.example", "/protobuf.SportLinesService/subscribeOnSportLinesServerStream")
+	require.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// requests other than gRPC-Web go to the HTTP API
+	resp = preflight(dashboardOrigin, linesPath)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
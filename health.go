@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const healthCheckInterval = time.Second
+
+// healthReporter keeps the statuses of the gRPC health service in line with the line puller and the storage.
+type healthReporter struct {
+	server  *health.Server
+	isReady func() linePullerStatus
+	storage storage
+}
+
+func newHealthReporter(server *health.Server, isReady func() linePullerStatus, storage storage) *healthReporter {
+	return &healthReporter{
+		server:  server,
+		isReady: isReady,
+		storage: storage,
+	}
+}
+
+// update serves both the whole server and SportLinesService only if all sports were pulled and the storage is up.
+// The storage is pinged first, since the readiness check reads from it and mustn't run against a dead database.
+func (r *healthReporter) update() {
+	status := healthpb.HealthCheckResponse_SERVING
+
+	err := r.storage.Ping()
+	if err != nil {
+		log.Warn("storage is unavailable: ", err)
+
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	} else if r.isReady() != ready {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	r.server.SetServingStatus("", status)
+	r.server.SetServingStatus(_SportLinesService_serviceDesc.ServiceName, status)
+}
+
+func (r *healthReporter) run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		r.update()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// shutdown reports NOT_SERVING for good, so that clients stop sending new streams before the server stops.
+func (r *healthReporter) shutdown() {
+	r.server.Shutdown()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func requireServingStatus(t *testing.T, server *health.Server, expected healthpb.HealthCheckResponse_ServingStatus) {
+	for _, service := range []string{"", _SportLinesService_serviceDesc.ServiceName} {
+		resp, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		require.Equal(t, expected, resp.Status)
+	}
+}
+
+func TestHealthReporter(t *testing.T) {
+	status := notReady
+	server := health.NewServer()
+	reporter := newHealthReporter(server, func() linePullerStatus { return status }, newMapStorage())
+
+	reporter.update()
+	requireServingStatus(t, server, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	status = ready
+	reporter.update()
+	requireServingStatus(t, server, healthpb.HealthCheckResponse_SERVING)
+
+	reporter.shutdown()
+	reporter.update()
+	requireServingStatus(t, server, healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+// unavailableStorage fails to ping like a database which went down.
+type unavailableStorage struct {
+	*mapStorage
+}
+
+func (unavailableStorage) Ping() error {
+	return errors.New("connection refused")
+}
+
+func TestHealthReporter_StorageUnavailable(t *testing.T) {
+	isReadyCalled := false
+	server := health.NewServer()
+	reporter := newHealthReporter(server, func() linePullerStatus {
+		isReadyCalled = true
+
+		return ready
+	}, unavailableStorage{mapStorage: newMapStorage()})
+
+	reporter.update()
+	requireServingStatus(t, server, healthpb.HealthCheckResponse_NOT_SERVING)
+	require.False(t, isReadyCalled)
+}
@@ -0,0 +1,796 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// encodedResponse is marshalled at most once, no matter how many streams it is sent to.
+type encodedResponse struct {
+	once sync.Once
+	resp *SportLinesResponse
+	data []byte
+	err  error
+	// tick is the span of the tick which read the lines of the response, if a tick did
+	tick trace.SpanContext
+}
+
+func newEncodedResponse(resp *SportLinesResponse) *encodedResponse {
+	return &encodedResponse{
+		once: sync.Once{},
+		resp: resp,
+		data: nil,
+		err:  nil,
+		tick: trace.SpanContext{},
+	}
+}
+
+func (e *encodedResponse) marshal() ([]byte, error) {
+	e.once.Do(func() {
+		e.data, e.err = proto.Marshal(e.resp)
+	})
+
+	return e.data, e.err
+}
+
+// responseGroupIDField is the number of SportLinesResponse.groupId.
+const responseGroupIDField protowire.Number = 7
+
+// outboundResponse is a response queued for one group of a stream.
+type outboundResponse struct {
+	resp    *encodedResponse
+	groupID string
+}
+
+func (o *outboundResponse) marshal() ([]byte, error) {
+	data, err := o.resp.marshal()
+	if err != nil || o.groupID == "" {
+		return data, err
+	}
+
+	// fields may go in any order, so the group id is appended to the shared encoding instead of encoding it again
+	size := len(data) + protowire.SizeTag(responseGroupIDField) + protowire.SizeBytes(len(o.groupID))
+	tagged := make([]byte, len(data), size)
+	copy(tagged, data)
+	tagged = protowire.AppendTag(tagged, responseGroupIDField, protowire.BytesType)
+	tagged = protowire.AppendString(tagged, o.groupID)
+
+	return tagged, nil
+}
+
+// message returns the response with its group id for encodings other than proto.
+func (o *outboundResponse) message() *SportLinesResponse {
+	if o.groupID == "" {
+		return o.resp.resp
+	}
+
+	resp := proto.Clone(o.resp.resp).(*SportLinesResponse)
+	resp.GroupId = o.groupID
+
+	return resp
+}
+
+// responseMessage returns the response a stream is asked to send, whatever form it is queued in.
+func responseMessage(m interface{}) *SportLinesResponse {
+	switch m := m.(type) {
+	case *outboundResponse:
+		return m.message()
+	case *encodedResponse:
+		return m.resp
+	}
+
+	return m.(*SportLinesResponse)
+}
+
+// sharedResponse is the response without copying it, so its group ID may be missing and it must not be modified.
+func sharedResponse(m interface{}) *SportLinesResponse {
+	switch m := m.(type) {
+	case *outboundResponse:
+		return m.resp.resp
+	case *encodedResponse:
+		return m.resp
+	}
+
+	return m.(*SportLinesResponse)
+}
+
+// responseTick is the span of the tick the response was made on, it's invalid for other responses.
+func responseTick(m interface{}) trace.SpanContext {
+	switch m := m.(type) {
+	case *outboundResponse:
+		return m.resp.tick
+	case *encodedResponse:
+		return m.tick
+	}
+
+	return trace.SpanContext{}
+}
+
+// responseKind is the kind of the response without copying it.
+func responseKind(m interface{}) SportLinesResponse_Kind {
+	return sharedResponse(m).Kind
+}
+
+// encodedResponseCodec is a proto codec which sends already encoded responses as is.
+// It's forced on the server only, so clients in the same process keep the default codec.
+type encodedResponseCodec struct{}
+
+func (encodedResponseCodec) Marshal(v interface{}) ([]byte, error) {
+	switch e := v.(type) {
+	case *outboundResponse:
+		return e.marshal()
+	case *encodedResponse:
+		return e.marshal()
+	}
+
+	return proto.Marshal(v.(proto.Message))
+}
+
+func (encodedResponseCodec) Unmarshal(data []byte, v interface{}) error {
+	return proto.Unmarshal(data, v.(proto.Message))
+}
+
+func (encodedResponseCodec) Name() string {
+	return "proto"
+}
+
+// subscriber is a group of a stream, all groups of the stream share its queue.
+type subscriber struct {
+	ctx     context.Context
+	queue   *outboundQueue
+	groupID string
+	// hub groups of the subscription, one per interval
+	groups       []*subscriptionGroup
+	sportNames   map[string]struct{}
+	deliveryMode DeliveryMode
+}
+
+func newSubscriber(ctx context.Context, queue *outboundQueue, groupID string) *subscriber {
+	return &subscriber{
+		ctx:          ctx,
+		queue:        queue,
+		groupID:      groupID,
+		groups:       nil,
+		sportNames:   nil,
+		deliveryMode: DeliveryMode_DELTAS,
+	}
+}
+
+func (sub *subscriber) deliver(resp *encodedResponse) {
+	sub.queue.push(&outboundResponse{
+		resp:    resp,
+		groupID: sub.groupID,
+	})
+}
+
+// lines returns the lines which the client has after applying every response sent to it.
+// Every tick of a group is sent to all of its subscribers, so they are the lines of the subscriber's groups.
+func (sub *subscriber) lines() map[string]float64 {
+	if len(sub.groups) == 1 {
+		lines, _, _ := sub.groups[0].linesOf(sub)
+
+		return lines
+	}
+
+	lines := make(map[string]float64, len(sub.sportNames))
+
+	for _, g := range sub.groups {
+		groupLines, _, _ := g.linesOf(sub)
+		for sportName, line := range groupLines {
+			lines[sportName] = line
+		}
+	}
+
+	return lines
+}
+
+// snapshot returns the lines of the subscriber's groups as absolute ones.
+func (sub *subscriber) snapshot(sportNames map[string]struct{}) *SportLinesResponse {
+	lines := sub.lines()
+	sequence := uint64(0)
+	pullTimes := make(map[string]*timestamppb.Timestamp, len(lines))
+
+	for _, g := range sub.groups {
+		groupLines, groupPullTimes, groupSequence := g.linesOf(sub)
+		if groupSequence > sequence {
+			sequence = groupSequence
+		}
+
+		for sportName := range groupLines {
+			pullTimes[sportName] = timestamppb.New(groupPullTimes[sportName])
+		}
+	}
+
+	return &SportLinesResponse{
+		SportNameToLine:     lines,
+		Sequence:            sequence,
+		Kind:                SportLinesResponse_SNAPSHOT,
+		PendingSportNames:   pendingSportNames(sportNames, lines),
+		SportNameToPullTime: pullTimes,
+	}
+}
+
+type groupKey struct {
+	interval   time.Duration
+	sportNames string
+}
+
+func newGroupKey(interval time.Duration, sportNames map[string]struct{}) groupKey {
+	names := make([]string, 0, len(sportNames))
+	for sportName := range sportNames {
+		names = append(names, sportName)
+	}
+
+	sort.Strings(names)
+
+	return groupKey{
+		interval:   interval,
+		sportNames: strings.Join(names, ","),
+	}
+}
+
+// subscriptionGroup serves all subscribers with the same interval and sport set.
+// They share the lines they were sent last, so every tick produces a single response for all of them.
+type subscriptionGroup struct {
+	key        groupKey
+	sportNames map[string]struct{}
+	lines      map[string]float64
+	// pull times of the observation the lines were taken from, it may have other sports too
+	pullTimes   map[string]time.Time
+	sequence    uint64
+	subscribers map[*subscriber]struct{}
+	// subscribers which joined after the last tick were sent fresher lines than the group has
+	joined   map[*subscriber]*lineSnapshot
+	nextTick time.Time
+}
+
+// lineSnapshot is the lines observed at a sequence together with their pull times,
+// like the ones a tick reads or the ones a subscriber was sent when it joined a group between its ticks.
+type lineSnapshot struct {
+	lines     map[string]float64
+	pullTimes map[string]time.Time
+	sequence  uint64
+}
+
+// linesOf returns the lines the subscriber was sent last, their pull times and sequence.
+func (g *subscriptionGroup) linesOf(sub *subscriber) (map[string]float64, map[string]time.Time, uint64) {
+	if j, exists := g.joined[sub]; exists {
+		return j.lines, j.pullTimes, j.sequence
+	}
+
+	return g.lines, g.pullTimes, g.sequence
+}
+
+// advance schedules the next tick, skipping the ones which were missed.
+func (g *subscriptionGroup) advance(now time.Time) {
+	g.nextTick = g.nextTick.Add(g.key.interval)
+	if g.nextTick.After(now) {
+		return
+	}
+
+	missed := now.Sub(g.nextTick)/g.key.interval + 1
+	g.nextTick = g.nextTick.Add(missed * g.key.interval)
+}
+
+// newTickResponse shapes the lines of a tick for the delivery mode, nil means that nothing is sent.
+// The response is shared by the subscribers, so the span of the tick is set before anyone gets it.
+func newTickResponse(
+	mode DeliveryMode,
+	lines, deltas map[string]float64,
+	pullTimes map[string]time.Time,
+	sequence uint64,
+	pending []string,
+	tick trace.SpanContext,
+) *encodedResponse {
+	resp := &SportLinesResponse{
+		SportNameToLine:   deltas,
+		Sequence:          sequence,
+		Kind:              SportLinesResponse_DELTA,
+		PendingSportNames: pending,
+	}
+
+	switch mode {
+	case DeliveryMode_DELTAS:
+	case DeliveryMode_CHANGED_ONLY:
+		changed := make(map[string]float64, len(deltas))
+		for sportName, delta := range deltas {
+			if delta != 0 {
+				changed[sportName] = delta
+			}
+		}
+
+		if len(changed) == 0 {
+			return nil
+		}
+
+		resp.SportNameToLine = changed
+	case DeliveryMode_ABSOLUTE:
+		resp.SportNameToLine = lines
+		resp.Kind = SportLinesResponse_SNAPSHOT
+	}
+
+	resp.SportNameToPullTime = pullTimestamps(resp.SportNameToLine, pullTimes)
+
+	encoded := newEncodedResponse(resp)
+	encoded.tick = tick
+
+	return encoded
+}
+
+// pullTimestamps returns the pull times of the sports which have lines in a response.
+func pullTimestamps(lines map[string]float64, pullTimes map[string]time.Time) map[string]*timestamppb.Timestamp {
+	timestamps := make(map[string]*timestamppb.Timestamp, len(lines))
+	for sportName := range lines {
+		timestamps[sportName] = timestamppb.New(pullTimes[sportName])
+	}
+
+	return timestamps
+}
+
+// batchTickResponses joins the responses of the groups of one subscriber which are due on the same tick.
+// They have the same kind and disjoint sports.
+func batchTickResponses(responses []*encodedResponse) *encodedResponse {
+	if len(responses) == 1 {
+		return responses[0]
+	}
+
+	batched := &SportLinesResponse{
+		SportNameToLine:     make(map[string]float64),
+		Sequence:            0,
+		Kind:                responses[0].resp.Kind,
+		SportNameToPullTime: make(map[string]*timestamppb.Timestamp),
+	}
+
+	for _, resp := range responses {
+		for sportName, line := range resp.resp.SportNameToLine {
+			batched.SportNameToLine[sportName] = line
+		}
+
+		for sportName, pulledAt := range resp.resp.SportNameToPullTime {
+			batched.SportNameToPullTime[sportName] = pulledAt
+		}
+
+		if resp.resp.Sequence > batched.Sequence {
+			batched.Sequence = resp.resp.Sequence
+		}
+
+		batched.PendingSportNames = append(batched.PendingSportNames, resp.resp.PendingSportNames...)
+	}
+
+	sort.Strings(batched.PendingSportNames)
+
+	encoded := newEncodedResponse(batched)
+	encoded.tick = responses[0].tick
+
+	return encoded
+}
+
+// subscriptionHub polls the storage once per group tick instead of once per stream
+// and fans the same encoded response out to every subscriber of the group.
+// A single scheduler ticks all groups, so groups which are due at the same time are ticked together.
+type subscriptionHub struct {
+	sync.Mutex
+	storage     storage
+	lineLog     *lineChangeLog
+	groups      map[groupKey]*subscriptionGroup
+	isRunning   bool
+	rescheduled chan struct{}
+}
+
+func newSubscriptionHub(storage storage, lineLog *lineChangeLog) *subscriptionHub {
+	return &subscriptionHub{
+		Mutex:       sync.Mutex{},
+		storage:     storage,
+		lineLog:     lineLog,
+		groups:      make(map[groupKey]*subscriptionGroup),
+		isRunning:   false,
+		rescheduled: make(chan struct{}, 1),
+	}
+}
+
+// safeTick keeps the scheduler going if a tick panics, the groups which didn't advance skip the tick.
+// The hub must not be locked.
+func (h *subscriptionHub) safeTick(groups []*subscriptionGroup, now time.Time) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		logPanic("hub", r)
+
+		h.Lock()
+		defer h.Unlock()
+
+		for _, g := range groups {
+			if !g.nextTick.After(now) {
+				g.advance(now)
+			}
+		}
+	}()
+
+	h.tick(groups, now)
+}
+
+// run ticks the due groups and sleeps until the next tick. It returns when there are no groups left.
+func (h *subscriptionHub) run() {
+	for {
+		h.Lock()
+		now := time.Now()
+		due := make([]*subscriptionGroup, 0, 1)
+
+		for _, g := range h.groups {
+			if !g.nextTick.After(now) {
+				due = append(due, g)
+			}
+		}
+		h.Unlock()
+
+		if len(due) != 0 {
+			h.safeTick(due, now)
+		}
+
+		h.Lock()
+		if len(h.groups) == 0 {
+			h.isRunning = false
+			h.Unlock()
+
+			return
+		}
+
+		var nextTick time.Time
+
+		for _, g := range h.groups {
+			if nextTick.IsZero() || g.nextTick.Before(nextTick) {
+				nextTick = g.nextTick
+			}
+		}
+		h.Unlock()
+
+		timer := time.NewTimer(time.Until(nextTick))
+		select {
+		case <-timer.C:
+		case <-h.rescheduled:
+		}
+		timer.Stop()
+	}
+}
+
+// reschedule wakes the scheduler up after the groups have changed.
+func (h *subscriptionHub) reschedule() {
+	select {
+	case h.rescheduled <- struct{}{}:
+	default:
+	}
+}
+
+// tick sends the lines of the due groups, the storage is polled once for all of them.
+// The storage is read before the hub is locked, so a slow storage doesn't hold up other groups and subscriptions.
+// The hub must not be locked.
+// The tick is shared by the streams, so it's a trace of its own which their sends link to.
+func (h *subscriptionHub) tick(groups []*subscriptionGroup, now time.Time) {
+	ctx, span := startSpan(context.Background(), "hub.tick", attribute.Int("groups", len(groups)))
+	defer span.End()
+
+	// sports of a group never change, so they can be read without the lock
+	sportNames := make(map[string]struct{})
+
+	for _, g := range groups {
+		for sportName := range g.sportNames {
+			sportNames[sportName] = struct{}{}
+		}
+	}
+
+	observedLines, pullTimes, sequence := h.lineLog.observe(ctx, h.storage, sportNames)
+
+	h.Lock()
+	defer h.Unlock()
+
+	h.fanOut(groups, &lineSnapshot{
+		lines:     observedLines,
+		pullTimes: pullTimes,
+		sequence:  sequence,
+	}, span.SpanContext(), now)
+}
+
+// fanOut sends the lines observed by a tick to the subscribers of the groups.
+// A subscriber whose groups are due together gets a single response. The hub must be locked.
+func (h *subscriptionHub) fanOut(
+	groups []*subscriptionGroup,
+	observed *lineSnapshot,
+	tick trace.SpanContext,
+	now time.Time,
+) {
+	observedLines, pullTimes, sequence := observed.lines, observed.pullTimes, observed.sequence
+	subToResponses := make(map[*subscriber][]*encodedResponse)
+
+	for _, g := range groups {
+		lines := selectLines(observedLines, g.sportNames)
+		deltas := diffLines(lines, g.lines)
+		pending := pendingSportNames(g.sportNames, lines)
+		modeToResp := make(map[DeliveryMode]*encodedResponse, 1)
+		g.lines = lines
+		g.pullTimes = pullTimes
+		g.sequence = sequence
+		g.advance(now)
+
+		for sub := range g.subscribers {
+			resp, exists := modeToResp[sub.deliveryMode]
+
+			switch j, hasJoined := g.joined[sub]; {
+			case hasJoined:
+				resp = newTickResponse(sub.deliveryMode, lines, diffLines(lines, j.lines), pullTimes, sequence, pending, tick)
+			case !exists:
+				resp = newTickResponse(sub.deliveryMode, lines, deltas, pullTimes, sequence, pending, tick)
+				modeToResp[sub.deliveryMode] = resp
+			}
+
+			switch {
+			case resp == nil:
+			case len(sub.groups) == 1:
+				sub.deliver(resp)
+			default:
+				subToResponses[sub] = append(subToResponses[sub], resp)
+			}
+		}
+
+		g.joined = nil
+	}
+
+	for sub, responses := range subToResponses {
+		sub.deliver(batchTickResponses(responses))
+	}
+}
+
+// subscribe moves the subscriber to the groups of the given subscription, one per interval.
+// The subscriber gets a snapshot if its sport set was replaced and deltas to the lines of the new groups otherwise.
+// If the change is incremental, only the added sports get absolute lines.
+func (h *subscriptionHub) subscribe(
+	sub *subscriber,
+	subscription subscription,
+	resumeFrom uint64,
+	incremental bool,
+) {
+	// the storage is read before the hub is locked, the lines are compared to the groups' ones under the lock
+	observedLines, pullTimes, sequence := h.lineLog.observe(sub.ctx, h.storage, subscription.sportNames)
+
+	h.Lock()
+	defer h.Unlock()
+
+	isSubscribed := len(sub.groups) != 0
+	prevLines := sub.lines()
+	h.leave(sub)
+
+	now := time.Now()
+	newGroups := make([]*subscriptionGroup, 0, 1)
+	joinedGroups := make([]*subscriptionGroup, 0, 1)
+
+	for interval, sportNames := range subscription.intervalToSportNames() {
+		key := newGroupKey(interval, sportNames)
+
+		g, exists := h.groups[key]
+		if !exists {
+			g = &subscriptionGroup{
+				key:         key,
+				sportNames:  sportNames,
+				lines:       nil,
+				pullTimes:   nil,
+				sequence:    0,
+				subscribers: make(map[*subscriber]struct{}),
+				joined:      nil,
+				nextTick:    now.Add(interval),
+			}
+			h.groups[key] = g
+			newGroups = append(newGroups, g)
+		} else {
+			joinedGroups = append(joinedGroups, g)
+		}
+
+		g.subscribers[sub] = struct{}{}
+		sub.groups = append(sub.groups, g)
+	}
+
+	// the lines of existing groups are as old as their last tick, so the subscriber gets fresh ones
+	for _, g := range newGroups {
+		g.lines = selectLines(observedLines, g.sportNames)
+		g.pullTimes = pullTimes
+		g.sequence = sequence
+	}
+
+	for _, g := range joinedGroups {
+		lines := selectLines(observedLines, g.sportNames)
+		if sameLines(lines, g.lines) {
+			continue
+		}
+
+		if g.joined == nil {
+			g.joined = make(map[*subscriber]*lineSnapshot)
+		}
+
+		g.joined[sub] = &lineSnapshot{
+			lines:     lines,
+			pullTimes: pullTimes,
+			sequence:  sequence,
+		}
+	}
+
+	if len(newGroups) != 0 {
+		if h.isRunning {
+			h.reschedule()
+		} else {
+			h.isRunning = true
+
+			go h.run()
+		}
+	}
+
+	sportNames := subscription.sportNames
+	resp := sub.snapshot(sportNames)
+	lines := resp.SportNameToLine
+
+	switch {
+	case subscription.deliveryMode == DeliveryMode_ABSOLUTE:
+	case !isSubscribed && resumeFrom != 0:
+		resumedLines, resumed := h.lineLog.linesAt(sportNames, resumeFrom)
+		if resumed {
+			resp.SportNameToLine = diffLines(lines, resumedLines)
+			resp.Kind = SportLinesResponse_DELTA
+		} else {
+			log.Infof("can't resume subscription from sequence %d, sending snapshot", resumeFrom)
+			resp.Kind = SportLinesResponse_GAP_SNAPSHOT
+		}
+	case !isSubscribed, !incremental && !sameSportNames(sub.sportNames, sportNames):
+	default:
+		resp.SportNameToLine, resp.AbsoluteSportNames = diffSubscribedLines(lines, prevLines, sub.sportNames)
+		resp.Kind = SportLinesResponse_DELTA
+	}
+
+	sub.sportNames = sportNames
+	sub.deliveryMode = subscription.deliveryMode
+	sub.deliver(newEncodedResponse(resp))
+}
+
+// snapshot observes the current lines and their pull times,
+// the sequence can be used to resume a subscription from them.
+func (h *subscriptionHub) snapshot(
+	ctx context.Context,
+	sportNames map[string]struct{},
+) (map[string]float64, map[string]time.Time, uint64) {
+	return h.lineLog.observe(ctx, h.storage, sportNames)
+}
+
+// resync sends the subscriber a snapshot of the lines it has, so that a client which has drifted gets them again.
+func (h *subscriptionHub) resync(sub *subscriber) {
+	h.Lock()
+	defer h.Unlock()
+
+	sub.deliver(newEncodedResponse(sub.snapshot(sub.sportNames)))
+}
+
+// unsubscribe removes the subscriber from its groups and stops the groups it was the last one in.
+func (h *subscriptionHub) unsubscribe(sub *subscriber) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.leave(sub)
+}
+
+func (h *subscriptionHub) leave(sub *subscriber) {
+	isStopped := false
+
+	for _, g := range sub.groups {
+		delete(g.subscribers, sub)
+		delete(g.joined, sub)
+
+		if len(g.subscribers) == 0 {
+			delete(h.groups, g.key)
+
+			isStopped = true
+		}
+	}
+
+	sub.groups = nil
+
+	if isStopped {
+		h.reschedule()
+	}
+}
+
+// selectLines returns the lines of the given sports.
+func selectLines(lines map[string]float64, sportNames map[string]struct{}) map[string]float64 {
+	selected := make(map[string]float64, len(sportNames))
+
+	for sportName := range sportNames {
+		if line, exists := lines[sportName]; exists {
+			selected[sportName] = line
+		}
+	}
+
+	return selected
+}
+
+func diffLines(lines, prevLines map[string]float64) map[string]float64 {
+	deltas := make(map[string]float64, len(lines))
+	for sportName, line := range lines {
+		deltas[sportName] = line - prevLines[sportName]
+	}
+
+	return deltas
+}
+
+// diffSubscribedLines returns deltas for the sports which were subscribed before
+// and absolute lines together with sorted names for the ones which weren't.
+func diffSubscribedLines(
+	lines, prevLines map[string]float64,
+	prevSportNames map[string]struct{},
+) (map[string]float64, []string) {
+	deltas := make(map[string]float64, len(lines))
+
+	var absoluteSportNames []string
+
+	for sportName, line := range lines {
+		if _, exists := prevSportNames[sportName]; exists {
+			deltas[sportName] = line - prevLines[sportName]
+		} else {
+			deltas[sportName] = line
+			absoluteSportNames = append(absoluteSportNames, sportName)
+		}
+	}
+
+	sort.Strings(absoluteSportNames)
+
+	return deltas, absoluteSportNames
+}
+
+// pendingSportNames returns sorted subscribed sports which have no lines yet.
+func pendingSportNames(sportNames map[string]struct{}, lines map[string]float64) []string {
+	var pending []string
+
+	for sportName := range sportNames {
+		if _, exists := lines[sportName]; !exists {
+			pending = append(pending, sportName)
+		}
+	}
+
+	sort.Strings(pending)
+
+	return pending
+}
+
+func sameLines(a, b map[string]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for sportName, line := range a {
+		if prevLine, exists := b[sportName]; !exists || prevLine != line {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameSportNames(a, b map[string]struct{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for sportName := range a {
+		if _, exists := b[sportName]; !exists {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionHub_SharedGroup(t *testing.T) {
+	s := newMapStorage()
+	s.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	h := newSubscriptionHub(s, newLineChangeLog(defaultReplayBufferSize))
+	sportNames := map[string]struct{}{soccerSport: {}}
+
+	first := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+	second := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+
+	h.subscribe(first, subscription{sportNames: sportNames, interval: time.Hour}, 0, false)
+	h.subscribe(second, subscription{sportNames: sportNames, interval: time.Hour}, 0, false)
+
+	require.Equal(t, 1, len(h.groups))
+	require.Equal(t, first.groups, second.groups)
+	require.Equal(t, map[string]float64{soccerSport: 0.5}, popResponse(first).resp.SportNameToLine)
+	require.Equal(t, map[string]float64{soccerSport: 0.5}, popResponse(second).resp.SportNameToLine)
+
+	s.Upload(context.Background(), soccerSport, 0.75, time.Now())
+	tickGroups(h, first.groups...)
+
+	firstResp := popResponse(first)
+	secondResp := popResponse(second)
+	require.True(t, firstResp == secondResp)
+	require.Equal(t, SportLinesResponse_DELTA, firstResp.resp.Kind)
+	require.Equal(t, map[string]float64{soccerSport: 0.25}, firstResp.resp.SportNameToLine)
+}
+
+func TestSubscriptionHub_TickSpan(t *testing.T) {
+	recordSpans(t)
+
+	s := newMapStorage()
+	s.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	h := newSubscriptionHub(s, newLineChangeLog(defaultReplayBufferSize))
+	sportNames := map[string]struct{}{soccerSport: {}}
+
+	first := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+	second := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+
+	h.subscribe(first, subscription{sportNames: sportNames, interval: time.Hour}, 0, false)
+	h.subscribe(second, subscription{sportNames: sportNames, interval: time.Hour}, 0, false)
+	popResponse(first)
+	popResponse(second)
+
+	tickGroups(h, first.groups...)
+	firstTick := popResponse(first).tick
+	require.True(t, firstTick.IsValid())
+
+	// a response which was already handed out keeps the span of its own tick
+	tickGroups(h, first.groups...)
+	require.Equal(t, firstTick, popResponse(second).tick)
+	require.NotEqual(t, firstTick, popResponse(first).tick)
+}
+
+func TestSubscriptionHub_JoinExistingGroup(t *testing.T) {
+	s := newMapStorage()
+	s.Upload(context.Background(), soccerSport, 1, time.Now())
+	h := newSubscriptionHub(s, newLineChangeLog(defaultReplayBufferSize))
+	sportNames := map[string]struct{}{soccerSport: {}}
+
+	first := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+	second := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+
+	h.subscribe(first, subscription{sportNames: sportNames, interval: time.Hour}, 0, false)
+	popResponse(first)
+
+	// the group's lines are from its last tick, the joining subscriber gets the stored ones
+	s.Upload(context.Background(), soccerSport, 2, time.Now())
+	h.subscribe(second, subscription{sportNames: sportNames, interval: time.Hour}, 0, false)
+	require.Equal(t, map[string]float64{soccerSport: 2}, popResponse(second).resp.SportNameToLine)
+
+	s.Upload(context.Background(), soccerSport, 2.5, time.Now())
+	tickGroups(h, first.groups...)
+
+	require.Equal(t, map[string]float64{soccerSport: 1.5}, popResponse(first).resp.SportNameToLine)
+	require.Equal(t, map[string]float64{soccerSport: 0.5}, popResponse(second).resp.SportNameToLine)
+
+	s.Upload(context.Background(), soccerSport, 3, time.Now())
+	tickGroups(h, first.groups...)
+
+	// once both have the group's lines they share the response again
+	require.True(t, popResponse(first) == popResponse(second))
+}
+
+func TestSubscriptionHub_IntervalChange(t *testing.T) {
+	s := newMapStorage()
+	s.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	h := newSubscriptionHub(s, newLineChangeLog(defaultReplayBufferSize))
+	sportNames := map[string]struct{}{soccerSport: {}}
+	sub := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+
+	h.subscribe(sub, subscription{sportNames: sportNames, interval: time.Hour}, 0, false)
+	popResponse(sub)
+
+	s.Upload(context.Background(), soccerSport, 0.75, time.Now())
+	h.subscribe(sub, subscription{sportNames: sportNames, interval: 2 * time.Hour}, 0, false)
+
+	resp := popResponse(sub)
+	require.Equal(t, 1, len(h.groups))
+	require.Equal(t, SportLinesResponse_DELTA, resp.resp.Kind)
+	require.Equal(t, map[string]float64{soccerSport: 0.25}, resp.resp.SportNameToLine)
+}
+
+func TestSubscriptionHub_Unsubscribe(t *testing.T) {
+	s := newMapStorage()
+	s.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	h := newSubscriptionHub(s, newLineChangeLog(defaultReplayBufferSize))
+	sub := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+
+	h.subscribe(sub, subscription{sportNames: map[string]struct{}{soccerSport: {}}, interval: time.Hour}, 0, false)
+	popResponse(sub)
+
+	h.unsubscribe(sub)
+	require.Equal(t, 0, len(h.groups))
+	require.Nil(t, sub.groups)
+
+	deadline := time.Now().Add(5 * time.Second)
+	isRunning := true
+
+	for isRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		h.Lock()
+		isRunning = h.isRunning
+		h.Unlock()
+	}
+
+	require.False(t, isRunning)
+}
+
+func BenchmarkSubscriptionHub_Tick(b *testing.B) {
+	for _, subscriberCount := range []int{10, 100, 1000, 10000} {
+		b.Run(fmt.Sprintf("subscribers=%d", subscriberCount), func(b *testing.B) {
+			s := newMapStorage()
+			s.Upload(context.Background(), soccerSport, 0.5, time.Now())
+			s.Upload(context.Background(), footballSport, 0.5, time.Now())
+			h := newSubscriptionHub(s, newLineChangeLog(defaultReplayBufferSize))
+			sportNames := map[string]struct{}{soccerSport: {}, footballSport: {}}
+
+			subs := make([]*subscriber, subscriberCount)
+			for i := range subs {
+				subs[i] = newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+				h.subscribe(subs[i], subscription{sportNames: sportNames, interval: time.Hour}, 0, false)
+				popResponse(subs[i])
+			}
+
+			groups := subs[0].groups
+			codec := encodedResponseCodec{}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i != b.N; i++ {
+				s.Upload(context.Background(), soccerSport, float64(i), time.Now())
+				tickGroups(h, groups...)
+
+				for _, sub := range subs {
+					_, err := codec.Marshal(popResponse(sub))
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*subscriberCount), "ns/subscriber")
+		})
+	}
+}
+
+func tickGroups(h *subscriptionHub, groups ...*subscriptionGroup) {
+	h.tick(groups, time.Now())
+}
+
+// panickingStorage breaks once it's switched on, like a bug in a tick would.
+type panickingStorage struct {
+	*mapStorage
+	isBroken bool
+}
+
+func (s *panickingStorage) Get(ctx context.Context, key string) (float64, time.Time, bool) {
+	if s.isBroken {
+		panic("storage is broken")
+	}
+
+	return s.mapStorage.Get(ctx, key)
+}
+
+func TestSubscriptionHub_TickPanic(t *testing.T) {
+	s := &panickingStorage{mapStorage: newMapStorage(), isBroken: false}
+	s.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	h := newSubscriptionHub(s, newLineChangeLog(defaultReplayBufferSize))
+	sub := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+
+	h.subscribe(sub, subscription{sportNames: map[string]struct{}{soccerSport: {}}, interval: time.Hour}, 0, false)
+
+	s.isBroken = true
+	now := time.Now().Add(time.Hour)
+
+	require.NotPanics(t, func() { h.safeTick(sub.groups, now) })
+
+	// the broken tick is skipped, so the scheduler doesn't spin on it
+	require.True(t, sub.groups[0].nextTick.After(now))
+}
+
+// blockingStorage holds reads once it's switched on until they are released, like a slow database.
+type blockingStorage struct {
+	*mapStorage
+	isBlocking bool
+	started    chan struct{}
+	released   chan struct{}
+}
+
+func (s *blockingStorage) Get(ctx context.Context, key string) (float64, time.Time, bool) {
+	if s.isBlocking {
+		s.started <- struct{}{}
+		<-s.released
+	}
+
+	return s.mapStorage.Get(ctx, key)
+}
+
+func TestSubscriptionHub_SlowStorage(t *testing.T) {
+	s := &blockingStorage{
+		mapStorage: newMapStorage(),
+		isBlocking: false,
+		started:    make(chan struct{}, 1),
+		released:   make(chan struct{}),
+	}
+	s.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	h := newSubscriptionHub(s, newLineChangeLog(defaultReplayBufferSize))
+	first := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+	second := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+
+	h.subscribe(first, subscription{sportNames: map[string]struct{}{soccerSport: {}}, interval: time.Hour}, 0, false)
+	h.subscribe(second, subscription{sportNames: map[string]struct{}{soccerSport: {}}, interval: 2 * time.Hour}, 0, false)
+	popResponse(first)
+
+	s.isBlocking = true
+	ticked := make(chan struct{})
+
+	go func() {
+		tickGroups(h, first.groups...)
+		close(ticked)
+	}()
+	<-s.started
+
+	// the tick waits for the storage without holding the hub
+	unsubscribed := make(chan struct{})
+
+	go func() {
+		h.unsubscribe(second)
+		close(unsubscribed)
+	}()
+
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second):
+		t.Fatal("unsubscribe is blocked by the tick")
+	}
+
+	close(s.released)
+	<-ticked
+	require.Equal(t, SportLinesResponse_DELTA, popResponse(first).resp.Kind)
+}
+
+func popResponse(sub *subscriber) *encodedResponse {
+	resp, _ := sub.queue.pop()
+
+	return resp.resp
+}
+
+func TestOutboundResponse_GroupID(t *testing.T) {
+	resp := newEncodedResponse(&SportLinesResponse{
+		SportNameToLine: map[string]float64{soccerSport: 0.5},
+		Sequence:        1,
+		Kind:            SportLinesResponse_DELTA,
+	})
+	codec := encodedResponseCodec{}
+
+	for _, groupID := range []string{"", "live", "outrights"} {
+		data, err := codec.Marshal(&outboundResponse{
+			resp:    resp,
+			groupID: groupID,
+		})
+		require.NoError(t, err)
+
+		decoded := &SportLinesResponse{}
+		require.NoError(t, codec.Unmarshal(data, decoded))
+		require.Equal(t, groupID, decoded.GroupId)
+		require.Equal(t, map[string]float64{soccerSport: 0.5}, decoded.SportNameToLine)
+	}
+}
+
+func TestSubscriptionHub_ChangedOnly(t *testing.T) {
+	s := newMapStorage()
+	s.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	s.Upload(context.Background(), footballSport, 0.5, time.Now())
+	h := newSubscriptionHub(s, newLineChangeLog(defaultReplayBufferSize))
+	sub := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+
+	h.subscribe(sub, subscription{
+		sportNames:   map[string]struct{}{soccerSport: {}, footballSport: {}},
+		interval:     time.Hour,
+		deliveryMode: DeliveryMode_CHANGED_ONLY,
+	}, 0, false)
+	popResponse(sub)
+
+	tickGroups(h, sub.groups...)
+
+	_, exists := sub.queue.pop()
+	require.False(t, exists)
+
+	s.Upload(context.Background(), soccerSport, 0.75, time.Now())
+	tickGroups(h, sub.groups...)
+
+	resp := popResponse(sub)
+	require.Equal(t, map[string]float64{soccerSport: 0.25}, resp.resp.SportNameToLine)
+}
+
+func TestSubscriptionHub_SportIntervals(t *testing.T) {
+	s := newMapStorage()
+	s.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	s.Upload(context.Background(), footballSport, 0.5, time.Now())
+	h := newSubscriptionHub(s, newLineChangeLog(defaultReplayBufferSize))
+	sub := newSubscriber(context.Background(), newOutboundQueue(defaultQueueSize, coalesce), "")
+
+	h.subscribe(sub, subscription{
+		sportNames:          map[string]struct{}{soccerSport: {}, footballSport: {}},
+		interval:            time.Hour,
+		sportNameToInterval: map[string]time.Duration{footballSport: 2 * time.Hour},
+	}, 0, false)
+
+	resp := popResponse(sub)
+	require.Equal(t, 2, len(h.groups))
+	require.Equal(t, SportLinesResponse_SNAPSHOT, resp.resp.Kind)
+	require.Equal(t, map[string]float64{soccerSport: 0.5, footballSport: 0.5}, resp.resp.SportNameToLine)
+
+	s.Upload(context.Background(), soccerSport, 0.75, time.Now())
+	s.Upload(context.Background(), footballSport, 0.75, time.Now())
+
+	hourly := h.groups[newGroupKey(time.Hour, map[string]struct{}{soccerSport: {}})]
+	tickGroups(h, hourly)
+
+	resp = popResponse(sub)
+	require.Equal(t, map[string]float64{soccerSport: 0.25}, resp.resp.SportNameToLine)
+
+	s.Upload(context.Background(), soccerSport, 1, time.Now())
+	tickGroups(h, sub.groups...)
+
+	resp = popResponse(sub)
+	require.Equal(t, map[string]float64{soccerSport: 0.25, footballSport: 0.25}, resp.resp.SportNameToLine)
+
+	_, exists := sub.queue.pop()
+	require.False(t, exists)
+}
+
+func TestSubscriptionGroup_Advance(t *testing.T) {
+	start := time.Now()
+	g := &subscriptionGroup{
+		key:      groupKey{interval: time.Second, sportNames: soccerSport},
+		nextTick: start,
+	}
+
+	g.advance(start)
+	require.Equal(t, start.Add(time.Second), g.nextTick)
+
+	g.advance(start.Add(3500 * time.Millisecond))
+	require.Equal(t, start.Add(4*time.Second), g.nextTick)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var internalError = status.Error(codes.Internal, "internal error")
+
+// callDetails is filled in by inner interceptors for the access log.
+type callDetails struct {
+	principal string
+}
+
+type callDetailsKey struct{}
+
+func callDetailsFromContext(ctx context.Context) *callDetails {
+	details, _ := ctx.Value(callDetailsKey{}).(*callDetails)
+
+	return details
+}
+
+// observeCall writes the access log entry and the metrics of a finished call.
+func observeCall(ctx context.Context, details *callDetails, method string, start time.Time, err error) {
+	duration := time.Since(start)
+	code := status.Code(err)
+
+	grpcCalls.WithLabelValues(method, code.String()).Inc()
+	grpcCallDuration.WithLabelValues(method).Observe(duration.Seconds())
+
+	fields := log.Fields{
+		"method":   method,
+		"code":     code.String(),
+		"duration": duration.String(),
+	}
+
+	if p, exists := peer.FromContext(ctx); exists {
+		fields["peer"] = p.Addr.String()
+	}
+
+	if details.principal != "" {
+		fields["principal"] = details.principal
+	}
+
+	log.WithFields(fields).Info("gRPC call finished")
+}
+
+func accessLogUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	start := time.Now()
+	details := &callDetails{}
+
+	resp, err := handler(context.WithValue(ctx, callDetailsKey{}, details), req)
+	observeCall(ctx, details, info.FullMethod, start, err)
+
+	return resp, err
+}
+
+func accessLogStreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	start := time.Now()
+	details := &callDetails{}
+	ctx := context.WithValue(ss.Context(), callDetailsKey{}, details)
+
+	err := handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	observeCall(ctx, details, info.FullMethod, start, err)
+
+	return err
+}
+
+// recoverPanic turns a panic of a handler into an Internal error, so that one call can't bring the server down.
+func recoverPanic(method string, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	panics.WithLabelValues("handler").Inc()
+	log.WithField("method", method).Errorf("panic in gRPC handler: %v\n%s", r, debug.Stack())
+
+	*err = internalError
+}
+
+// logPanic reports a panic recovered in a goroutine, recover must be called by the deferred function itself.
+func logPanic(goroutine string, r interface{}) {
+	panics.WithLabelValues(goroutine).Inc()
+	log.WithField("goroutine", goroutine).Errorf("panic: %v\n%s", r, debug.Stack())
+}
+
+func recoveryUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp interface{}, err error) {
+	defer recoverPanic(info.FullMethod, &err)
+
+	return handler(ctx, req)
+}
+
+func recoveryStreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) (err error) {
+	defer recoverPanic(info.FullMethod, &err)
+
+	return handler(srv, ss)
+}
+
+// interceptorOptions go before any other interceptors, so that they see every call and its final code.
+func interceptorOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(accessLogUnaryInterceptor, tracingUnaryInterceptor, recoveryUnaryInterceptor),
+		grpc.ChainStreamInterceptor(accessLogStreamInterceptor, tracingStreamInterceptor, recoveryStreamInterceptor),
+	}
+}
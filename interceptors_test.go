@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoveryInterceptors(t *testing.T) {
+	_, err := recoveryUnaryInterceptor(
+		context.Background(),
+		nil,
+		&grpc.UnaryServerInfo{FullMethod: "/test/unary"},
+		func(context.Context, interface{}) (interface{}, error) {
+			panic("unary handler is broken")
+		},
+	)
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	err = recoveryStreamInterceptor(
+		nil,
+		nil,
+		&grpc.StreamServerInfo{FullMethod: "/test/stream"},
+		func(interface{}, grpc.ServerStream) error {
+			panic("stream handler is broken")
+		},
+	)
+	require.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestGRPCServer_CallMetrics(t *testing.T) {
+	method := "/protobuf.SportLinesService/subscribeOnSportLines"
+	callCount := func() float64 {
+		return testutil.ToFloat64(grpcCalls.WithLabelValues(method, codes.InvalidArgument.String()))
+	}
+	before := callCount()
+
+	serverAddr := initServer(t, newMapStorage(), nil)
+	stream := initClient(t, serverAddr)
+
+	err := stream.Send(&SportLinesRequest{
+		SportNames:   []string{"tennis"},
+		TimeInterval: 1,
+	})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	require.Equal(t, before+1, callCount())
+
+	w := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, w.Body.String(), `sportlines_grpc_call_duration_seconds_count{method="`+method+`"}`)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const defaultMaxGroups = 16
+
+var (
+	tooManyStreamsError gRPCServerError = status.Error(codes.ResourceExhausted, "too many concurrent streams")
+	tooManyGroupsError  gRPCServerError = status.Error(codes.ResourceExhausted, "too many subscription groups")
+	tooManySportsError  gRPCServerError = status.Error(codes.ResourceExhausted, "too many sports in subscription")
+	requestRateError    gRPCServerError = status.Error(codes.ResourceExhausted, "requests are sent too often")
+)
+
+// clientLimits apply to every principal, or to every IP address if authentication is disabled.
+// Zero values disable the corresponding limit.
+type clientLimits struct {
+	MaxStreams int `json:"maxStreams"`
+	// groups on a stream
+	MaxGroups int `json:"maxGroups"`
+	// sports in all groups of a stream
+	MaxSportNames int `json:"maxSportNames"`
+	// requests per second on a stream
+	RequestRate  float64 `json:"requestRate"`
+	RequestBurst int     `json:"requestBurst"`
+}
+
+type clientUsage struct {
+	Streams          int `json:"streams"`
+	Requests         int `json:"requests"`
+	RejectedRequests int `json:"rejectedRequests"`
+}
+
+// clientLimiter tracks what every client uses, clients without streams are forgotten.
+type clientLimiter struct {
+	sync.Mutex
+	limits        clientLimits
+	clientToUsage map[string]*clientUsage
+}
+
+func newClientLimiter(limits clientLimits) *clientLimiter {
+	return &clientLimiter{
+		Mutex:         sync.Mutex{},
+		limits:        limits,
+		clientToUsage: make(map[string]*clientUsage),
+	}
+}
+
+// clientName is the principal of the stream if it's authenticated and the IP address of the peer otherwise.
+func clientName(ctx context.Context) string {
+	if p := principalFromContext(ctx); p != nil {
+		return p.name
+	}
+
+	p, exists := peer.FromContext(ctx)
+	if !exists {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+// acquireStream counts a new stream of the client unless it has too many of them already.
+func (l *clientLimiter) acquireStream(client string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	usage, exists := l.clientToUsage[client]
+	if !exists {
+		usage = &clientUsage{}
+		l.clientToUsage[client] = usage
+	}
+
+	if l.limits.MaxStreams != 0 && usage.Streams >= l.limits.MaxStreams {
+		log.Infof("%s has too many streams", client)
+
+		if usage.Streams == 0 {
+			delete(l.clientToUsage, client)
+		}
+
+		return tooManyStreamsError
+	}
+
+	usage.Streams++
+
+	return nil
+}
+
+func (l *clientLimiter) releaseStream(client string) {
+	l.Lock()
+	defer l.Unlock()
+
+	usage := l.clientToUsage[client]
+	usage.Streams--
+
+	if usage.Streams == 0 {
+		delete(l.clientToUsage, client)
+	}
+}
+
+func (l *clientLimiter) recordRequest(client string, err error) {
+	l.Lock()
+	defer l.Unlock()
+
+	usage := l.clientToUsage[client]
+	usage.Requests++
+
+	if err != nil {
+		usage.RejectedRequests++
+	}
+}
+
+// checkSubscription checks the subscription of a group together with the other groups of the same stream.
+func (l *clientLimiter) checkSubscription(subscription subscription, otherGroups []subscription) error {
+	if l.limits.MaxGroups != 0 && len(otherGroups) >= l.limits.MaxGroups {
+		return tooManyGroupsError
+	}
+
+	sportCount := len(subscription.sportNames)
+	for _, other := range otherGroups {
+		sportCount += len(other.sportNames)
+	}
+
+	if l.limits.MaxSportNames != 0 && sportCount > l.limits.MaxSportNames {
+		return tooManySportsError
+	}
+
+	return nil
+}
+
+// newRequestBucket returns the request rate limiter of a new stream.
+func (l *clientLimiter) newRequestBucket() *tokenBucket {
+	return newTokenBucket(l.limits.RequestRate, l.limits.RequestBurst)
+}
+
+// status returns the usage of every client if the principal is nil and only the principal's usage otherwise.
+func (l *clientLimiter) status(p *principal) map[string]interface{} {
+	l.Lock()
+	defer l.Unlock()
+
+	clientToUsage := make(map[string]clientUsage, len(l.clientToUsage))
+	for client, usage := range l.clientToUsage {
+		if p == nil || p.name == client {
+			clientToUsage[client] = *usage
+		}
+	}
+
+	return map[string]interface{}{
+		"limits":  l.limits,
+		"clients": clientToUsage,
+	}
+}
+
+// statusHandler shows the usage of all clients only if authentication is disabled,
+// otherwise a principal sees its own usage, so that client identities don't leak.
+func statusHandler(l *clientLimiter, auth authenticators) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := auth.authenticateHTTP(r, "")
+		if err != nil {
+			writeJSONError(w, err)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		_ = json.NewEncoder(w).Encode(l.status(p))
+	}
+}
+
+// tokenBucket allows bursts of requests while keeping their average rate, a zero rate allows everything.
+// It isn't safe for concurrent use.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) take(now time.Time) bool {
+	if b.rate == 0 {
+		return true
+	}
+
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Now()
+	bucket := newTokenBucket(2, 2)
+	bucket.last = start
+
+	require.True(t, bucket.take(start))
+	require.True(t, bucket.take(start))
+	require.False(t, bucket.take(start))
+	require.False(t, bucket.take(start.Add(400*time.Millisecond)))
+	require.True(t, bucket.take(start.Add(600*time.Millisecond)))
+
+	require.True(t, newTokenBucket(0, 0).take(start))
+}
+
+func TestClientLimiter_Streams(t *testing.T) {
+	limiter := newClientLimiter(clientLimits{MaxStreams: 1})
+
+	require.NoError(t, limiter.acquireStream("alice"))
+	require.Equal(t, tooManyStreamsError, limiter.acquireStream("alice"))
+	require.NoError(t, limiter.acquireStream("bob"))
+
+	limiter.recordRequest("alice", nil)
+	limiter.recordRequest("alice", tooManySportsError)
+
+	recorder := httptest.NewRecorder()
+	statusHandler(limiter, nil)(recorder, httptest.NewRequest("GET", "/status", nil))
+	require.JSONEq(t, `{
+		"limits": {"maxStreams": 1, "maxGroups": 0, "maxSportNames": 0, "requestRate": 0, "requestBurst": 0},
+		"clients": {
+			"alice": {"streams": 1, "requests": 2, "rejectedRequests": 1},
+			"bob": {"streams": 1, "requests": 0, "rejectedRequests": 0}
+		}
+	}`, recorder.Body.String())
+
+	keys, err := loadStaticKeys(writeTestFile(t, "keys.json", `[{"token": "alice-token", "principal": "alice"}]`))
+	require.NoError(t, err)
+
+	recorder = httptest.NewRecorder()
+	statusHandler(limiter, authenticators{keys})(recorder, httptest.NewRequest("GET", "/status", nil))
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	// a principal sees only its own usage
+	recorder = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/status", nil)
+	req.Header.Set("Authorization", "Bearer alice-token")
+	statusHandler(limiter, authenticators{keys})(recorder, req)
+	require.JSONEq(t, `{
+		"limits": {"maxStreams": 1, "maxGroups": 0, "maxSportNames": 0, "requestRate": 0, "requestBurst": 0},
+		"clients": {"alice": {"streams": 1, "requests": 2, "rejectedRequests": 1}}
+	}`, recorder.Body.String())
+
+	limiter.releaseStream("alice")
+	require.NoError(t, limiter.acquireStream("alice"))
+
+	limiter.releaseStream("alice")
+	limiter.releaseStream("bob")
+	require.Empty(t, limiter.clientToUsage)
+}
+
+func TestGRPCServer_ClientLimits(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	storage.Upload(context.Background(), footballSport, 0.5, time.Now())
+	config := defaultStreamConfig()
+	config.limits = clientLimits{
+		MaxStreams:    1,
+		MaxSportNames: 1,
+		RequestRate:   1,
+		RequestBurst:  2,
+	}
+	serverAddr := initServerWithConfig(t, storage, config)
+	stream := initClient(t, serverAddr)
+
+	err := stream.Send(&SportLinesRequest{
+		SportNames:   []string{soccerSport},
+		TimeInterval: 1,
+	})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	second := initClient(t, serverAddr)
+	_, err = second.Recv()
+	require.Equal(t, tooManyStreamsError.Error(), err.Error())
+
+	err = stream.Send(&SportLinesRequest{
+		SportNames: []string{footballSport},
+		Action:     SportLinesRequest_ADD,
+	})
+	require.NoError(t, err)
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, SportLinesResponse_ERROR, resp.Kind)
+	require.Equal(t, tooManySportsError.Error(), status.FromProto(resp.Error).Err().Error())
+
+	err = stream.Send(&SportLinesRequest{
+		SportNames: []string{footballSport},
+		Action:     SportLinesRequest_ADD,
+	})
+	require.NoError(t, err)
+
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, SportLinesResponse_ERROR, resp.Kind)
+	require.Equal(t, int32(codes.ResourceExhausted), resp.Error.Code)
+	require.Equal(t, requestRateError.Error(), status.FromProto(resp.Error).Err().Error())
+}
+
+func TestGRPCServer_GroupLimits(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	storage.Upload(context.Background(), footballSport, 0.5, time.Now())
+	storage.Upload(context.Background(), baseballSport, 0.5, time.Now())
+	config := defaultStreamConfig()
+	config.limits = clientLimits{
+		MaxStreams:    0,
+		MaxGroups:     2,
+		MaxSportNames: 2,
+		RequestRate:   0,
+		RequestBurst:  0,
+	}
+	serverAddr := initServerWithConfig(t, storage, config)
+	stream := initClient(t, serverAddr)
+
+	requests := []struct {
+		req          *SportLinesRequest
+		expectedKind SportLinesResponse_Kind
+		expectedErr  error
+	}{
+		{
+			req:          &SportLinesRequest{SportNames: []string{soccerSport}, TimeInterval: 60, GroupId: "live"},
+			expectedKind: SportLinesResponse_SNAPSHOT,
+			expectedErr:  nil,
+		},
+		{
+			req:          &SportLinesRequest{SportNames: []string{footballSport}, TimeInterval: 60, GroupId: "outrights"},
+			expectedKind: SportLinesResponse_SNAPSHOT,
+			expectedErr:  nil,
+		},
+		// the third group is one too many even though its sport fits
+		{
+			req:          &SportLinesRequest{SportNames: []string{baseballSport}, TimeInterval: 60, GroupId: "extra"},
+			expectedKind: SportLinesResponse_ERROR,
+			expectedErr:  tooManyGroupsError,
+		},
+		// sports of all groups of the stream are counted together
+		{
+			req: &SportLinesRequest{
+				SportNames: []string{baseballSport},
+				Action:     SportLinesRequest_ADD,
+				GroupId:    "live",
+			},
+			expectedKind: SportLinesResponse_ERROR,
+			expectedErr:  tooManySportsError,
+		},
+		{
+			req: &SportLinesRequest{
+				SportNames:   []string{baseballSport},
+				TimeInterval: 60,
+				Action:       SportLinesRequest_REPLACE,
+				GroupId:      "live",
+			},
+			expectedKind: SportLinesResponse_SNAPSHOT,
+			expectedErr:  nil,
+		},
+	}
+
+	for _, r := range requests {
+		require.NoError(t, stream.Send(r.req))
+
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, r.req.GroupId, resp.GroupId)
+		require.Equal(t, r.expectedKind, resp.Kind, resp.Error.GetMessage())
+
+		if r.expectedErr != nil {
+			require.Equal(t, int32(codes.ResourceExhausted), resp.Error.Code)
+			require.Equal(t, r.expectedErr.Error(), status.FromProto(resp.Error).Err().Error())
+		}
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -12,6 +13,9 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 type linePullerStatus int
@@ -37,7 +41,7 @@ func newLinePuller(
 	sportNames []string,
 	storage storage,
 	wg *sync.WaitGroup,
-	sportNameToPullingInterval map[string]int32,
+	sportNameToPullingInterval map[string]time.Duration,
 ) *linePuller {
 	lp := &linePuller{
 		Mutex:              sync.Mutex{},
@@ -55,7 +59,7 @@ func newLinePuller(
 		if !exists {
 			log.Fatal("interval for sport is not set")
 		}
-		go lp.StartLinePullerWorker(ctx, lp.linesProviderAddr, sportName, time.NewTicker(time.Second*time.Duration(interval)))
+		go lp.StartLinePullerWorker(ctx, lp.linesProviderAddr, sportName, time.NewTicker(interval))
 	}
 	lp.Unlock()
 
@@ -76,40 +80,85 @@ PullingLoop:
 			break PullingLoop
 		case <-ticker.C:
 		}
-		r, err := http.NewRequestWithContext(ctx, "GET", linesProviderAddr+sportName, nil)
-		if err != nil {
-			log.Fatal(err)
-		}
-		resp, err := http.DefaultClient.Do(r)
-		if err != nil {
-			log.Fatal("could not connect to lines provider: ", err)
-		}
-		body, _ := ioutil.ReadAll(resp.Body)
-		defer resp.Body.Close()
-		linesMap := map[string]interface{}{}
-		err = json.Unmarshal(body, &linesMap)
-		if err != nil {
-			log.Fatal("lines provider sent data which couldn't be unmarshalled as map: ", err)
-		}
-		sportMap, ok := linesMap["lines"].(map[string]interface{})
-		if !ok {
-			log.Fatal(fmt.Sprintf("could not unpack %s map: ", sportName), err)
-		}
-		sportLine, ok := sportMap[strings.ToUpper(sportName)].(string)
-		if !ok {
-			log.Fatal("sport name doesn't exist in line provider")
-		}
-		sportLineDouble, err := strconv.ParseFloat(sportLine, 64)
+
+		start := time.Now()
+		pullCtx, span := startSpan(ctx, "linesProvider.pull", attribute.String("sport", sportName))
+		sportLine, err := lp.pull(pullCtx, linesProviderAddr, sportName)
+		pulledAt := time.Now()
+		pullDuration.WithLabelValues(sportName).Observe(pulledAt.Sub(start).Seconds())
+
+		lp.Lock()
+		lp.isLineProviderDown = err != nil
+		lp.Unlock()
+
 		if err != nil {
-			log.Fatal("cannot convert sportline to double")
+			endSpan(span, err)
+
+			if ctx.Err() != nil {
+				break PullingLoop
+			}
+
+			pullErrors.WithLabelValues(sportName).Inc()
+			log.Errorf("could not pull the line for %s: %v", sportName, err)
+
+			continue
 		}
-		lp.storage.Upload(sportName, sportLineDouble)
-		log.Debug(fmt.Sprintf("pulled the line for %s with value %s", sportName, sportLine))
+
+		// the upload is a part of the pull, so its span goes under the pull one
+		lp.storage.Upload(pullCtx, sportName, sportLine, pulledAt)
+		endSpan(span, nil)
+		lineValue.WithLabelValues(sportName).Set(sportLine)
+		log.Debug(fmt.Sprintf("pulled the line for %s with value %v", sportName, sportLine))
 	}
 	log.Infof("worker for %s is shut down", sportName)
 	lp.wg.Done()
 }
 
+// pull gets the current line of the sport from the lines provider.
+func (lp *linePuller) pull(ctx context.Context, linesProviderAddr, sportName string) (float64, error) {
+	r, err := http.NewRequestWithContext(ctx, "GET", linesProviderAddr+sportName, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		return 0, fmt.Errorf("could not connect to lines provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	linesMap := map[string]interface{}{}
+
+	err = json.Unmarshal(body, &linesMap)
+	if err != nil {
+		return 0, fmt.Errorf("lines provider sent data which couldn't be unmarshalled as map: %w", err)
+	}
+
+	sportMap, ok := linesMap["lines"].(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("could not unpack %s map", sportName)
+	}
+
+	sportLine, ok := sportMap[strings.ToUpper(sportName)].(string)
+	if !ok {
+		return 0, errors.New("sport name doesn't exist in line provider")
+	}
+
+	sportLineDouble, err := strconv.ParseFloat(sportLine, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot convert sportline to double: %w", err)
+	}
+
+	return sportLineDouble, nil
+}
+
 func (lp *linePuller) isReady() linePullerStatus {
 	lp.Lock()
 	defer lp.Unlock()
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -20,12 +24,41 @@ func TestLinePuller_IsReady(t *testing.T) {
 
 	require.Equal(t, notReady, lp.isReady())
 
-	s.Upload("soccer", 0)
+	s.Upload(context.Background(), "soccer", 0, time.Now())
 	require.Equal(t, notReady, lp.isReady())
 
-	s.Upload("soccer", 0)
+	s.Upload(context.Background(), "soccer", 0, time.Now())
 	require.Equal(t, notReady, lp.isReady())
 
-	s.Upload("football", 0)
+	s.Upload(context.Background(), "football", 0, time.Now())
 	require.Equal(t, ready, lp.isReady())
 }
+
+func TestLinePuller_Pull(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/soccer" {
+			_, _ = w.Write([]byte(`{"lines": {"SOCCER": "0.5"}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"lines": {}}`))
+	}))
+	defer provider.Close()
+
+	lp := &linePuller{
+		Mutex:              sync.Mutex{},
+		linesProviderAddr:  provider.URL + "/",
+		sportNames:         []string{soccerSport, footballSport},
+		storage:            newMapStorage(),
+		isLineProviderDown: false,
+		wg:                 nil,
+	}
+
+	line, err := lp.pull(context.Background(), lp.linesProviderAddr, soccerSport)
+	require.NoError(t, err)
+	require.Equal(t, 0.5, line)
+
+	_, err = lp.pull(context.Background(), lp.linesProviderAddr, footballSport)
+	require.Error(t, err)
+}
@@ -3,20 +3,37 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "subscribe" {
+		err := runSubscribeCommand(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
 	httpAddr := flag.String("http", ":8090", "address for http server")
 	grpcAddr := flag.String("grpc", ":8091", "address for grpc server")
 	linesProviderAddr := flag.String(
@@ -25,18 +42,112 @@ func main() {
 		"address for lines provider server",
 	)
 
-	baseballInterval := flag.Int("baseball", 1, "interval for pulling baseball lines (seconds)")
-	footballInterval := flag.Int("football", 1, "interval for pulling football lines (seconds)")
-	soccerInterval := flag.Int("soccer", 1, "interval for pulling soccer lines (seconds)")
+	baseballInterval := secondsOrDuration(time.Second)
+	footballInterval := secondsOrDuration(time.Second)
+	soccerInterval := secondsOrDuration(time.Second)
+
+	flag.Var(&baseballInterval, "baseball", "interval for pulling baseball lines (seconds or duration like 500ms)")
+	flag.Var(&footballInterval, "football", "interval for pulling football lines (seconds or duration like 500ms)")
+	flag.Var(&soccerInterval, "soccer", "interval for pulling soccer lines (seconds or duration like 500ms)")
+
+	replayBufferSize := flag.Int(
+		"replay-buffer",
+		defaultReplayBufferSize,
+		"number of line changes kept per sport for resuming subscriptions",
+	)
+
+	queueSize := flag.Int("queue-size", defaultQueueSize, "number of responses queued for a client before overflow")
+	overflowPolicyName := flag.String(
+		"overflow-policy",
+		"coalesce",
+		"what to do when client's queue overflows, allowed options: drop-oldest, coalesce, disconnect",
+	)
+
+	heartbeatInterval := flag.Duration(
+		"heartbeat-interval",
+		defaultHeartbeatInterval,
+		"interval after which an idle stream gets a heartbeat, 0 disables heartbeats",
+	)
+	idleTimeout := flag.Duration(
+		"idle-timeout",
+		defaultIdleTimeout,
+		"time for a client to send its first request before the stream is closed, 0 disables the timeout",
+	)
+	maxStreamAge := flag.Duration(
+		"max-stream-age",
+		0,
+		"age after which a stream asks the client to reconnect and ends, 0 disables the limit",
+	)
+
+	authKeysPath := flag.String(
+		"auth-keys",
+		"",
+		"JSON file with bearer tokens and the sports and minimum intervals allowed to them",
+	)
+	authJWTKeyPath := flag.String(
+		"auth-jwt-key",
+		"",
+		"PEM RSA or ECDSA public key file for verifying JWT bearer tokens",
+	)
+	authJWTSecretPath := flag.String(
+		"auth-jwt-secret",
+		"",
+		"HMAC secret file for verifying JWT bearer tokens, anyone who knows it can sign tokens",
+	)
+
+	maxStreams := flag.Int("max-streams", 0, "concurrent streams allowed to a principal or IP address, 0 is unlimited")
+	maxGroups := flag.Int("max-groups", defaultMaxGroups, "subscription groups allowed on a stream, 0 is unlimited")
+	maxSportNames := flag.Int("max-sports", 0, "sports allowed in all groups of a stream, 0 is unlimited")
+	maxRequestRate := flag.Float64(
+		"max-request-rate",
+		0,
+		"requests per second allowed on a stream, 0 is unlimited",
+	)
+	requestBurst := flag.Int("request-burst", 5, "requests allowed on a stream at once above the request rate")
+
+	tlsCertPath := flag.String("tls-cert", "", "PEM certificate for grpc and http listeners, enables TLS")
+	tlsKeyPath := flag.String("tls-key", "", "PEM private key of the TLS certificate")
+	tlsClientCAPath := flag.String(
+		"tls-client-ca",
+		"",
+		"PEM CA which client certificates must be signed by, enables mutual TLS",
+	)
+
+	corsOrigins := flag.String(
+		"cors-origins",
+		"",
+		"comma separated origins allowed to call gRPC-Web and WebSocket APIs from browsers, * allows any origin",
+	)
+
+	defaultOTLPProtocol := os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	if defaultOTLPProtocol == "" {
+		defaultOTLPProtocol = otlpGRPC
+	}
+
+	otlpEndpoint := flag.String(
+		"otlp-endpoint",
+		os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		"URL of the OTLP collector which trace spans are exported to, empty disables the export",
+	)
+	otlpProtocol := flag.String(
+		"otlp-protocol",
+		defaultOTLPProtocol,
+		"OTLP protocol, allowed options: grpc, http/protobuf",
+	)
+	traceOutput := flag.String(
+		"trace-output",
+		"",
+		"stdout or a file path which trace spans are written to as JSON lines for debugging, it isn't OTLP",
+	)
 
 	logLevel := flag.String("log", "info", "log level, allowed options: debug, info, warn, error, fatal")
 
 	flag.Parse()
 
-	sportNameToPullingInterval := make(map[string]int32)
-	sportNameToPullingInterval["baseball"] = int32(*baseballInterval)
-	sportNameToPullingInterval["football"] = int32(*footballInterval)
-	sportNameToPullingInterval["soccer"] = int32(*soccerInterval)
+	sportNameToPullingInterval := make(map[string]time.Duration)
+	sportNameToPullingInterval["baseball"] = time.Duration(baseballInterval)
+	sportNameToPullingInterval["football"] = time.Duration(footballInterval)
+	sportNameToPullingInterval["soccer"] = time.Duration(soccerInterval)
 
 	log.SetFormatter(&log.TextFormatter{
 		FullTimestamp: true,
@@ -56,6 +167,90 @@ func main() {
 		log.Fatalf("unknown log level: %s", *logLevel)
 	}
 
+	if *replayBufferSize < 1 {
+		log.Fatal("replay buffer size must be positive")
+	}
+
+	if *queueSize < 1 {
+		log.Fatal("queue size must be positive")
+	}
+
+	if *maxStreams < 0 || *maxGroups < 0 || *maxSportNames < 0 || *maxRequestRate < 0 || *requestBurst < 1 {
+		log.Fatal("client limits can't be negative and request burst must be positive")
+	}
+
+	if *heartbeatInterval < 0 || *idleTimeout < 0 || *maxStreamAge < 0 {
+		log.Fatal("stream durations can't be negative")
+	}
+
+	overflowPolicy, err := parseOverflowPolicy(*overflowPolicyName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var auth authenticators
+
+	if *authKeysPath != "" {
+		keys, err := loadStaticKeys(*authKeysPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		auth = append(auth, keys)
+	}
+
+	if *authJWTKeyPath != "" {
+		verifier, err := loadJWTVerifier(*authJWTKeyPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		auth = append(auth, verifier)
+	}
+
+	if *authJWTSecretPath != "" {
+		verifier, err := loadJWTSecret(*authJWTSecretPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		auth = append(auth, verifier)
+	}
+
+	var certificates *certificateReloader
+
+	if *tlsCertPath != "" || *tlsKeyPath != "" || *tlsClientCAPath != "" {
+		if *tlsCertPath == "" || *tlsKeyPath == "" {
+			log.Fatal("TLS needs both a certificate and a key")
+		}
+
+		certificates, err = newCertificateReloader(*tlsCertPath, *tlsKeyPath, *tlsClientCAPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// clients with a verified certificate are authenticated by its subject
+		if *tlsClientCAPath != "" && *authKeysPath == "" {
+			auth = append(auth, verifiedCertificates{})
+		}
+	}
+
+	allowedOrigins := splitCommaSeparated(*corsOrigins)
+
+	tracing := tracingConfig{
+		otlpEndpoint: *otlpEndpoint,
+		otlpProtocol: *otlpProtocol,
+		output:       *traceOutput,
+	}
+
+	shutdownTracing := func(context.Context) error { return nil }
+	if tracing.isEnabled() {
+		shutdownTracing, err = initTracing(context.Background(), tracing)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	log.Infof(
 		"starting program (http_address: %s, grpc_address: %s, provider address: %s)",
 		*httpAddr,
@@ -63,7 +258,7 @@ func main() {
 		*linesProviderAddr,
 	)
 
-	storage := newDBStorage()
+	storage := newInstrumentedStorage("mysql", newDBStorage())
 
 	sportNames := []string{"baseball", "football", "soccer"}
 	ctx, cancelFunc := context.WithCancel(context.Background())
@@ -71,26 +266,76 @@ func main() {
 	lp := newLinePuller(ctx, *linesProviderAddr, sportNames, storage, wg, sportNameToPullingInterval)
 
 	// Start HTTP server
-	srv := &http.Server{Addr: *httpAddr}
+	// event streams never become idle, so they end with the context instead of blocking the shutdown
+	srv := &http.Server{
+		Addr:        *httpAddr,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
 	http.HandleFunc("/ready", readyHandler(lp))
 
+	// Start gRPC server
+	wg.Add(1)
+
+	grpcOptions := authOptions(auth)
+	if certificates != nil {
+		grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(certificates.serverConfig("h2"))))
+	}
+
+	grpcServer := newGRPCServer(grpcOptions...)
+	publisher := newSportLinesPublisherServer(
+		storage,
+		sportNameToPullingInterval,
+		newLineChangeLog(*replayBufferSize),
+		streamConfig{
+			queueSize:         *queueSize,
+			overflowPolicy:    overflowPolicy,
+			heartbeatInterval: *heartbeatInterval,
+			idleTimeout:       *idleTimeout,
+			maxAge:            *maxStreamAge,
+			limits: clientLimits{
+				MaxStreams:    *maxStreams,
+				MaxGroups:     *maxGroups,
+				MaxSportNames: *maxSportNames,
+				RequestRate:   *maxRequestRate,
+				RequestBurst:  *requestBurst,
+			},
+		},
+	)
+	RegisterSportLinesServiceServer(grpcServer, publisher)
+	http.HandleFunc("/status", statusHandler(publisher.limiter, auth))
+	http.Handle("/metrics", promhttp.Handler())
+
+	rest := newRESTHandler(publisher, auth)
+	http.Handle(linesPath, rest)
+	http.Handle(linesPath+"/", rest)
+	http.Handle(streamPath, newSSEHandler(publisher, auth))
+	http.Handle(webSocketPath, newWebSocketHandler(publisher, auth, allowedOrigins))
+	srv.Handler = newGRPCWebHandler(grpcServer, allowedOrigins, http.DefaultServeMux)
+
 	wg.Add(1)
 
 	go func() {
 		defer wg.Done()
-		_ = srv.ListenAndServe()
+		if certificates != nil {
+			srv.TLSConfig = certificates.serverConfig("h2", "http/1.1")
+			_ = srv.ListenAndServeTLS("", "")
+		} else {
+			_ = srv.ListenAndServe()
+		}
 
 		log.Info("server is shut down")
 	}()
 
-	// Start gRPC server
+	reflection.Register(grpcServer)
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	healthReporter := newHealthReporter(healthServer, lp.isReady, storage)
+
 	wg.Add(1)
 
-	grpcServer := grpc.NewServer()
-	RegisterSportLinesServiceServer(grpcServer, sportLinesPublisherServer{
-		storage:                    storage,
-		sportNameToPullingInterval: sportNameToPullingInterval,
-	})
+	go healthReporter.run(ctx, wg)
 
 	go func(s *grpc.Server, serverAddr string) {
 		defer wg.Done()
@@ -117,13 +362,55 @@ func main() {
 	log.Infof("received signal (%s), gracefully shutting down...", sig.String())
 	cancelFunc()
 
-	err := srv.Shutdown(context.TODO())
+	err = srv.Shutdown(context.TODO())
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	healthReporter.shutdown()
 	grpcServer.GracefulStop()
 	wg.Wait()
+
+	err = shutdownTracing(context.TODO())
+	if err != nil {
+		log.Error("can't export the remaining spans: ", err)
+	}
+}
+
+// secondsOrDuration is a flag value which accepts both whole seconds and duration strings.
+type secondsOrDuration time.Duration
+
+func (d *secondsOrDuration) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *secondsOrDuration) Set(value string) error {
+	duration, err := parseSecondsOrDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*d = secondsOrDuration(duration)
+
+	return nil
+}
+
+func parseSecondsOrDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, atoiErr := strconv.Atoi(value)
+		if atoiErr != nil {
+			return 0, err
+		}
+
+		duration = time.Duration(seconds) * time.Second
+	}
+
+	if duration <= 0 {
+		return 0, errors.New("interval must be positive")
+	}
+
+	return duration, nil
 }
 
 func readyHandler(lp *linePuller) http.HandlerFunc {
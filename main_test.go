@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSecondsOrDuration(t *testing.T) {
+	duration, err := parseSecondsOrDuration("2")
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Second, duration)
+
+	duration, err = parseSecondsOrDuration("250ms")
+	require.NoError(t, err)
+	require.Equal(t, 250*time.Millisecond, duration)
+
+	_, err = parseSecondsOrDuration("0")
+	require.Error(t, err)
+
+	_, err = parseSecondsOrDuration("soon")
+	require.Error(t, err)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/status"
+)
+
+// latencyBuckets are upper bounds in seconds, they go up to an hour because streams are counted as a whole.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 60, 300, 3600}
+
+var (
+	pullDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sportlines_pull_duration_seconds",
+		Help:    "Duration of pulling a line from the lines provider.",
+		Buckets: latencyBuckets,
+	}, []string{"sport"})
+	pullErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sportlines_pull_errors_total",
+		Help: "Pulls which didn't get a line from the lines provider.",
+	}, []string{"sport"})
+	storageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sportlines_storage_operation_duration_seconds",
+		Help:    "Duration of storage operations.",
+		Buckets: latencyBuckets,
+	}, []string{"backend", "operation"})
+	lineValue = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sportlines_line",
+		Help: "Last pulled line.",
+	}, []string{"sport"})
+	activeStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sportlines_active_streams",
+		Help: "Subscription streams being served over any transport.",
+	})
+	messagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sportlines_messages_sent_total",
+		Help: "Responses sent to subscribers.",
+	}, []string{"kind"})
+	sendErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sportlines_send_errors_total",
+		Help: "Responses which couldn't be sent to subscribers.",
+	})
+	deliveryLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sportlines_delivery_latency_seconds",
+		Help:    "Time from pulling a line to sending it to a subscriber.",
+		Buckets: latencyBuckets,
+	}, []string{"sport", "class"})
+	grpcCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sportlines_grpc_calls_total",
+		Help: "Finished gRPC calls.",
+	}, []string{"method", "code"})
+	grpcCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sportlines_grpc_call_duration_seconds",
+		Help:    "Duration of gRPC calls, streams are counted as a whole.",
+		Buckets: latencyBuckets,
+	}, []string{"method"})
+	queuedResponses = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sportlines_queued_responses",
+		Help: "Responses waiting in outbound queues of all streams.",
+	})
+	droppedResponses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sportlines_dropped_responses_total",
+		Help: "Responses dropped from full outbound queues.",
+	})
+	coalescedResponses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sportlines_coalesced_responses_total",
+		Help: "Responses merged in full outbound queues.",
+	})
+	slowConsumers = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sportlines_slow_consumer_disconnects_total",
+		Help: "Streams closed because their outbound queue overflowed.",
+	})
+	panics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sportlines_panics_total",
+		Help: "Panics recovered in gRPC handlers and in stream and hub goroutines.",
+	}, []string{"goroutine"})
+	validationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sportlines_validation_failures_total",
+		Help: "Rejected subscription requests.",
+	}, []string{"reason"})
+)
+
+// otherValidationReason labels rejections which aren't listed in validationReasons.
+const otherValidationReason = "other"
+
+// validationReasons are the only values of the reason label, so that its cardinality is bounded.
+var validationReasons = []struct {
+	err    gRPCServerError
+	reason string
+}{
+	{emptySportListError, "empty_sport_list"},
+	{duplicateError, "duplicate_sports"},
+	{unknownSportNameError, "unknown_sport"},
+	{notSubscribedError, "not_subscribed"},
+	{intervalError, "invalid_interval"},
+	{periodicityError, "interval_too_short"},
+	{unknownGroupError, "unknown_group"},
+	{sportNotAllowedError, "sport_not_allowed"},
+	{intervalNotAllowedError, "interval_not_allowed"},
+	{tooManyGroupsError, "too_many_groups"},
+	{tooManySportsError, "too_many_sports"},
+	{requestRateError, "request_rate"},
+}
+
+// validationReason finds the reason of a rejection by its status message, which field violations keep.
+func validationReason(err error) string {
+	message := status.Convert(err).Message()
+
+	for _, r := range validationReasons {
+		if status.Convert(r.err).Message() == message {
+			return r.reason
+		}
+	}
+
+	return otherValidationReason
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationReason(t *testing.T) {
+	require.Equal(t, "unknown_sport", validationReason(unknownSportNameError))
+	require.Equal(t, "interval_too_short", validationReason(withFieldViolation(
+		periodicityError,
+		"interval",
+		"sport soccer is pulled every 2s, interval can't be less than that",
+	)))
+	require.Equal(t, otherValidationReason, validationReason(errors.New("sport tennis is broken")))
+
+	reasons := make(map[string]struct{}, len(validationReasons))
+	for _, r := range validationReasons {
+		reasons[r.reason] = struct{}{}
+	}
+
+	require.Len(t, reasons, len(validationReasons))
+}
+
+func TestMetricsHandler(t *testing.T) {
+	storage := newInstrumentedStorage("map", newMapStorage())
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	serverAddr := initServerWith(t, newSportLinesPublisherServer(
+		storage,
+		nil,
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	))
+	stream := initClient(t, serverAddr)
+
+	require.NoError(t, stream.Send(&SportLinesRequest{SportNames: []string{"tennis"}, TimeInterval: 1}))
+	_, err := stream.Recv()
+	require.Error(t, err)
+
+	w := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Contains(
+		t,
+		w.Body.String(),
+		`sportlines_storage_operation_duration_seconds_count{backend="map",operation="upload"}`,
+	)
+	require.Contains(t, w.Body.String(), `sportlines_validation_failures_total{reason="unknown_sport"}`)
+	require.Contains(t, w.Body.String(), "# TYPE sportlines_active_streams gauge\n")
+}
+
+func TestMetricsHandler_DeliveryLatency(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	serverAddr := initServer(t, storage, nil)
+	stream := initClient(t, serverAddr)
+
+	require.NoError(t, stream.Send(&SportLinesRequest{SportNames: []string{soccerSport}, TimeInterval: 1}))
+	_, err := stream.Recv()
+	require.NoError(t, err)
+
+	// the latency is observed after the response is sent
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		promhttp.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		return strings.Contains(w.Body.String(), `sportlines_delivery_latency_seconds_count{class="grpc",sport="soccer"}`)
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const defaultQueueSize = 16
+
+type overflowPolicy int
+
+const (
+	// dropOldest drops the oldest queued response, so the client loses the deltas it contained.
+	dropOldest overflowPolicy = iota
+	// coalesce merges all queued responses into one.
+	coalesce
+	// disconnect closes the stream with RESOURCE_EXHAUSTED.
+	disconnect
+)
+
+func parseOverflowPolicy(policy string) (overflowPolicy, error) {
+	switch policy {
+	case "drop-oldest":
+		return dropOldest, nil
+	case "coalesce":
+		return coalesce, nil
+	case "disconnect":
+		return disconnect, nil
+	}
+
+	return 0, fmt.Errorf("unknown overflow policy: %s", policy)
+}
+
+// outboundQueue buffers responses of one stream, so that a slow client never blocks the hub.
+type outboundQueue struct {
+	sync.Mutex
+	capacity      int
+	policy        overflowPolicy
+	responses     []*outboundResponse
+	ready         chan struct{}
+	overflowed    chan struct{}
+	hasOverflowed bool
+}
+
+func newOutboundQueue(capacity int, policy overflowPolicy) *outboundQueue {
+	return &outboundQueue{
+		Mutex:         sync.Mutex{},
+		capacity:      capacity,
+		policy:        policy,
+		responses:     make([]*outboundResponse, 0, capacity),
+		ready:         make(chan struct{}, 1),
+		overflowed:    make(chan struct{}),
+		hasOverflowed: false,
+	}
+}
+
+func (q *outboundQueue) push(resp *outboundResponse) {
+	q.Lock()
+	defer q.Unlock()
+
+	if q.hasOverflowed {
+		return
+	}
+
+	if len(q.responses) >= q.capacity {
+		switch q.policy {
+		case dropOldest:
+			q.responses = q.responses[1:]
+			queuedResponses.Dec()
+			droppedResponses.Inc()
+		case coalesce:
+			coalesced := coalesceResponses(append(q.responses, resp))
+			// a response per group and an error per group may not fit, then the client can't be caught up
+			if len(coalesced) > q.capacity {
+				q.overflow()
+
+				return
+			}
+
+			coalescedResponses.Add(float64(len(q.responses)))
+			queuedResponses.Add(-float64(len(q.responses)))
+			q.responses = append(q.responses[:0], coalesced[:len(coalesced)-1]...)
+			queuedResponses.Add(float64(len(q.responses)))
+			resp = coalesced[len(coalesced)-1]
+		case disconnect:
+			q.overflow()
+
+			return
+		}
+	}
+
+	q.responses = append(q.responses, resp)
+	queuedResponses.Inc()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// overflow forgets the queued responses and makes the stream close, the queue must be locked.
+func (q *outboundQueue) overflow() {
+	q.hasOverflowed = true
+	queuedResponses.Add(-float64(len(q.responses)))
+	slowConsumers.Inc()
+	q.responses = nil
+	close(q.overflowed)
+}
+
+func (q *outboundQueue) pop() (*outboundResponse, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.responses) == 0 {
+		return nil, false
+	}
+
+	resp := q.responses[0]
+	q.responses[0] = nil
+	q.responses = q.responses[1:]
+	queuedResponses.Dec()
+
+	return resp, true
+}
+
+// close forgets the responses which weren't sent.
+func (q *outboundQueue) close() {
+	q.Lock()
+	defer q.Unlock()
+
+	queuedResponses.Add(-float64(len(q.responses)))
+	q.responses = nil
+	q.hasOverflowed = true
+}
+
+// coalesceResponses merges responses of every stream group into one which leads the client to the same lines.
+// Only the last error of a group is kept, errors go before the merged lines.
+func coalesceResponses(responses []*outboundResponse) []*outboundResponse {
+	coalesced := make([]*outboundResponse, 0, 1)
+	groupIDToError := make(map[string]int)
+	groupIDs := make([]string, 0, 1)
+	groupIDToResponses := make(map[string][]*encodedResponse)
+
+	for _, resp := range responses {
+		if resp.resp.resp.Kind == SportLinesResponse_ERROR {
+			if i, exists := groupIDToError[resp.groupID]; exists {
+				coalesced[i] = resp
+			} else {
+				groupIDToError[resp.groupID] = len(coalesced)
+				coalesced = append(coalesced, resp)
+			}
+
+			continue
+		}
+
+		if _, exists := groupIDToResponses[resp.groupID]; !exists {
+			groupIDs = append(groupIDs, resp.groupID)
+		}
+
+		groupIDToResponses[resp.groupID] = append(groupIDToResponses[resp.groupID], resp.resp)
+	}
+
+	for _, groupID := range groupIDs {
+		coalesced = append(coalesced, &outboundResponse{
+			resp:    mergeResponses(groupIDToResponses[groupID]),
+			groupID: groupID,
+		})
+	}
+
+	return coalesced
+}
+
+// mergeResponses merges consecutive responses of one group into one which leads the client to the same lines.
+func mergeResponses(responses []*encodedResponse) *encodedResponse {
+	merged := &SportLinesResponse{
+		SportNameToLine:     make(map[string]float64),
+		Sequence:            0,
+		Kind:                SportLinesResponse_DELTA,
+		SportNameToPullTime: make(map[string]*timestamppb.Timestamp),
+	}
+	absoluteSportNames := make(map[string]struct{})
+
+	for _, resp := range responses {
+		if resp.resp.Kind != SportLinesResponse_DELTA {
+			merged.SportNameToLine = make(map[string]float64, len(resp.resp.SportNameToLine))
+			merged.SportNameToPullTime = make(map[string]*timestamppb.Timestamp, len(resp.resp.SportNameToLine))
+			merged.Kind = resp.resp.Kind
+			absoluteSportNames = make(map[string]struct{})
+		}
+
+		for _, sportName := range resp.resp.AbsoluteSportNames {
+			merged.SportNameToLine[sportName] = 0
+			if merged.Kind == SportLinesResponse_DELTA {
+				absoluteSportNames[sportName] = struct{}{}
+			}
+		}
+
+		for sportName, line := range resp.resp.SportNameToLine {
+			merged.SportNameToLine[sportName] += line
+		}
+
+		for sportName, pulledAt := range resp.resp.SportNameToPullTime {
+			merged.SportNameToPullTime[sportName] = pulledAt
+		}
+
+		merged.Sequence = resp.resp.Sequence
+		merged.PendingSportNames = resp.resp.PendingSportNames
+	}
+
+	for sportName := range absoluteSportNames {
+		merged.AbsoluteSportNames = append(merged.AbsoluteSportNames, sportName)
+	}
+
+	sort.Strings(merged.AbsoluteSportNames)
+
+	resp := newEncodedResponse(merged)
+	resp.tick = responses[len(responses)-1].tick
+
+	return resp
+}
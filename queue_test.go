@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func queuedResponse(resp *SportLinesResponse) *outboundResponse {
+	return &outboundResponse{
+		resp:    newEncodedResponse(resp),
+		groupID: "",
+	}
+}
+
+func deltaResponse(sequence uint64, sportNameToLine map[string]float64) *outboundResponse {
+	return queuedResponse(&SportLinesResponse{
+		SportNameToLine: sportNameToLine,
+		Sequence:        sequence,
+		Kind:            SportLinesResponse_DELTA,
+	})
+}
+
+func TestOutboundQueue_DropOldest(t *testing.T) {
+	q := newOutboundQueue(2, dropOldest)
+
+	q.push(deltaResponse(1, nil))
+	q.push(deltaResponse(2, nil))
+	q.push(deltaResponse(3, nil))
+
+	resp, exists := q.pop()
+	require.True(t, exists)
+	require.Equal(t, uint64(2), resp.resp.resp.Sequence)
+
+	resp, exists = q.pop()
+	require.True(t, exists)
+	require.Equal(t, uint64(3), resp.resp.resp.Sequence)
+
+	_, exists = q.pop()
+	require.False(t, exists)
+}
+
+func TestOutboundQueue_Coalesce(t *testing.T) {
+	q := newOutboundQueue(2, coalesce)
+
+	q.push(queuedResponse(&SportLinesResponse{
+		SportNameToLine: map[string]float64{"soccer": 1},
+		Sequence:        1,
+		Kind:            SportLinesResponse_SNAPSHOT,
+	}))
+	q.push(deltaResponse(2, map[string]float64{"soccer": 0.5}))
+	q.push(deltaResponse(3, map[string]float64{"soccer": -0.25}))
+
+	resp, exists := q.pop()
+	require.True(t, exists)
+	require.Equal(t, uint64(3), resp.resp.resp.Sequence)
+	require.Equal(t, SportLinesResponse_SNAPSHOT, resp.resp.resp.Kind)
+	require.Equal(t, map[string]float64{"soccer": 1.25}, resp.resp.resp.SportNameToLine)
+
+	_, exists = q.pop()
+	require.False(t, exists)
+}
+
+func TestOutboundQueue_Disconnect(t *testing.T) {
+	q := newOutboundQueue(1, disconnect)
+
+	q.push(deltaResponse(1, nil))
+	q.push(deltaResponse(2, nil))
+
+	_, isOpen := <-q.overflowed
+	require.False(t, isOpen)
+
+	_, exists := q.pop()
+	require.False(t, exists)
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	policy, err := parseOverflowPolicy("disconnect")
+	require.NoError(t, err)
+	require.Equal(t, disconnect, policy)
+
+	_, err = parseOverflowPolicy("block")
+	require.Error(t, err)
+}
+
+func TestOutboundQueue_CoalesceKeepsErrors(t *testing.T) {
+	q := newOutboundQueue(2, coalesce)
+	errorResp := &outboundResponse{
+		resp:    newErrorResponse(unknownSportNameError),
+		groupID: "",
+	}
+
+	q.push(deltaResponse(1, map[string]float64{"soccer": 0.5}))
+	q.push(errorResp)
+	q.push(deltaResponse(2, map[string]float64{"soccer": 0.25}))
+
+	resp, exists := q.pop()
+	require.True(t, exists)
+	require.True(t, resp == errorResp)
+
+	resp, exists = q.pop()
+	require.True(t, exists)
+	require.Equal(t, map[string]float64{"soccer": 0.75}, resp.resp.resp.SportNameToLine)
+}
+
+func TestOutboundQueue_CoalesceAbsoluteLines(t *testing.T) {
+	q := newOutboundQueue(2, coalesce)
+
+	q.push(deltaResponse(1, map[string]float64{"soccer": 0.5}))
+	q.push(queuedResponse(&SportLinesResponse{
+		SportNameToLine:    map[string]float64{"soccer": 0.25, "football": 1},
+		Sequence:           2,
+		Kind:               SportLinesResponse_DELTA,
+		AbsoluteSportNames: []string{"football"},
+	}))
+	q.push(deltaResponse(3, map[string]float64{"soccer": 0.25, "football": 0.5}))
+
+	resp, exists := q.pop()
+	require.True(t, exists)
+	require.Equal(t, SportLinesResponse_DELTA, resp.resp.resp.Kind)
+	require.Equal(t, []string{"football"}, resp.resp.resp.AbsoluteSportNames)
+	require.Equal(t, map[string]float64{"soccer": 1, "football": 1.5}, resp.resp.resp.SportNameToLine)
+}
+
+func TestOutboundQueue_CoalesceStaysBounded(t *testing.T) {
+	q := newOutboundQueue(4, coalesce)
+
+	for i := 0; i != 100; i++ {
+		q.push(&outboundResponse{
+			resp:    newErrorResponse(unknownSportNameError),
+			groupID: "",
+		})
+	}
+
+	require.LessOrEqual(t, len(q.responses), 4)
+
+	// a response per group doesn't fit, so the client can't be caught up
+	for i := 0; i != 5; i++ {
+		q.push(&outboundResponse{
+			resp:    deltaResponse(uint64(i), nil).resp,
+			groupID: fmt.Sprint(i),
+		})
+	}
+
+	_, isOpen := <-q.overflowed
+	require.False(t, isOpen)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const linesPath = "/api/v1/lines"
+
+// codeToHTTPStatus maps gRPC codes the same way as grpc-gateway does.
+var codeToHTTPStatus = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+}
+
+type linesResponse struct {
+	Sequence            uint64               `json:"sequence"`
+	SportNameToLine     map[string]float64   `json:"sportNameToLine"`
+	SportNameToPullTime map[string]time.Time `json:"sportNameToPullTime"`
+	SportNames          []string             `json:"sportNames"`
+	PendingSportNames   []string             `json:"pendingSportNames"`
+}
+
+type lineResponse struct {
+	Sequence  uint64     `json:"sequence"`
+	SportName string     `json:"sportName"`
+	Line      float64    `json:"line"`
+	PulledAt  *time.Time `json:"pulledAt,omitempty"`
+	IsPending bool       `json:"isPending"`
+}
+
+type errorBody struct {
+	Error errorStatus `json:"error"`
+}
+
+// errorStatus is google.rpc.Status in the JSON error format of Google APIs.
+type errorStatus struct {
+	Code    int               `json:"code"`
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Details []json.RawMessage `json:"details,omitempty"`
+}
+
+// restHandler serves the current lines over HTTP JSON with the same validation and authorization as gRPC.
+// The sequence of a response can be used as resumeFrom of a subscription.
+type restHandler struct {
+	server sportLinesPublisherServer
+	auth   authenticators
+}
+
+func newRESTHandler(server sportLinesPublisherServer, auth authenticators) *restHandler {
+	return &restHandler{
+		server: server,
+		auth:   auth,
+	}
+}
+
+func (h *restHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+
+		return
+	}
+
+	p, err := h.auth.authenticateHTTP(r, "")
+	if err != nil {
+		writeJSONError(w, err)
+
+		return
+	}
+
+	if sportName := strings.TrimPrefix(r.URL.Path, linesPath+"/"); sportName != r.URL.Path {
+		h.serveLine(r.Context(), w, p, sportName)
+	} else {
+		h.serveLines(r.Context(), w, p, splitCommaSeparated(r.URL.Query().Get("sports")))
+	}
+}
+
+// serveLines serves the requested sports or all sports the principal is allowed to see.
+func (h *restHandler) serveLines(ctx context.Context, w http.ResponseWriter, p *principal, sportNames []string) {
+	if len(sportNames) == 0 {
+		for _, sportName := range h.server.registry.sortedSportNames() {
+			if p == nil || p.isAllowed(sportName) {
+				sportNames = append(sportNames, sportName)
+			}
+		}
+	}
+
+	requested, err := h.validateSportNames(ctx, p, sportNames)
+	if err != nil {
+		writeJSONError(w, err)
+
+		return
+	}
+
+	lines, pullTimes, sequence := h.server.hub.snapshot(ctx, requested)
+
+	writeJSON(w, http.StatusOK, linesResponse{
+		Sequence:            sequence,
+		SportNameToLine:     lines,
+		SportNameToPullTime: pullTimes,
+		SportNames:          sportNames,
+		PendingSportNames:   pendingSportNames(requested, lines),
+	})
+}
+
+func (h *restHandler) serveLine(ctx context.Context, w http.ResponseWriter, p *principal, sportName string) {
+	requested, err := h.validateSportNames(ctx, p, []string{sportName})
+	if err != nil {
+		writeJSONError(w, err)
+
+		return
+	}
+
+	lines, pullTimes, sequence := h.server.hub.snapshot(ctx, requested)
+	line, exists := lines[sportName]
+
+	var pulledAt *time.Time
+	if exists {
+		t := pullTimes[sportName]
+		pulledAt = &t
+	}
+
+	writeJSON(w, http.StatusOK, lineResponse{
+		Sequence:  sequence,
+		SportName: sportName,
+		Line:      line,
+		PulledAt:  pulledAt,
+		IsPending: !exists,
+	})
+}
+
+func (h *restHandler) validateSportNames(
+	ctx context.Context,
+	p *principal,
+	sportNames []string,
+) (map[string]struct{}, error) {
+	err := h.server.validateKnownSportNames(ctx, sportNames)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := make(map[string]struct{}, len(sportNames))
+
+	for _, sportName := range sportNames {
+		if _, exists := requested[sportName]; exists {
+			return nil, duplicateError
+		}
+
+		if p != nil && !p.isAllowed(sportName) {
+			return nil, sportNotAllowedError
+		}
+
+		requested[sportName] = struct{}{}
+	}
+
+	return requested, nil
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		log.Error("can't write JSON response: ", err)
+	}
+}
+
+// writeMethodNotAllowed rejects a request which isn't GET, it's an HTTP error without a gRPC code.
+func writeMethodNotAllowed(w http.ResponseWriter) {
+	w.Header().Set("Allow", http.MethodGet)
+	writeJSON(w, http.StatusMethodNotAllowed, errorBody{
+		Error: errorStatus{
+			Code:    http.StatusMethodNotAllowed,
+			Status:  "METHOD_NOT_ALLOWED",
+			Message: "only GET is supported",
+			Details: nil,
+		},
+	})
+}
+
+// writeJSONError writes the status of the error with the HTTP status which corresponds to its code.
+func writeJSONError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+
+	httpStatus, exists := codeToHTTPStatus[st.Code()]
+	if !exists {
+		httpStatus = http.StatusInternalServerError
+	}
+
+	body := errorBody{
+		Error: errorStatus{
+			Code:    httpStatus,
+			Status:  code.Code_name[int32(st.Code())],
+			Message: st.Message(),
+			Details: nil,
+		},
+	}
+
+	for _, detail := range st.Proto().Details {
+		data, err := protojson.Marshal(detail)
+		if err == nil {
+			body.Error.Details = append(body.Error.Details, data)
+		}
+	}
+
+	writeJSON(w, httpStatus, body)
+}
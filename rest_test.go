@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func initRESTHandler(t *testing.T, auth authenticators) *restHandler {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	storage.Upload(context.Background(), footballSport, 0.75, time.Now())
+
+	return newRESTHandler(newSportLinesPublisherServer(
+		storage,
+		map[string]time.Duration{baseballSport: time.Second},
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	), auth)
+}
+
+func serveREST(handler http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestRESTHandler_Lines(t *testing.T) {
+	handler := initRESTHandler(t, nil)
+
+	w := serveREST(handler, http.MethodGet, "/api/v1/lines", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var lines linesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &lines))
+	require.Equal(t, []string{baseballSport, footballSport, soccerSport}, lines.SportNames)
+	require.Equal(t, map[string]float64{soccerSport: 0.5, footballSport: 0.75}, lines.SportNameToLine)
+	require.Equal(t, []string{baseballSport}, lines.PendingSportNames)
+	require.NotZero(t, lines.Sequence)
+
+	w = serveREST(handler, http.MethodGet, "/api/v1/lines?sports=soccer", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var filtered linesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &filtered))
+	require.Equal(t, map[string]float64{soccerSport: 0.5}, filtered.SportNameToLine)
+
+	w = serveREST(handler, http.MethodGet, "/api/v1/lines/football", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var line lineResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &line))
+	require.Equal(t, footballSport, line.SportName)
+	require.Equal(t, 0.75, line.Line)
+	require.False(t, line.IsPending)
+}
+
+func TestRESTHandler_Errors(t *testing.T) {
+	handler := initRESTHandler(t, nil)
+
+	w := serveREST(handler, http.MethodGet, "/api/v1/lines/tennis", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.JSONEq(t, `{"error": {
+		"code": 400,
+		"status": "INVALID_ARGUMENT",
+		"message": "sport name is unknown",
+		"details": [{
+			"@type": "type.googleapis.com/google.rpc.BadRequest",
+			"fieldViolations": [{"field": "sportNames[0]", "description": "sport tennis is unknown"}]
+		}]
+	}}`, w.Body.String())
+
+	w = serveREST(handler, http.MethodGet, "/api/v1/lines?sports=soccer,soccer", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveREST(handler, http.MethodPost, "/api/v1/lines", "")
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Equal(t, http.MethodGet, w.Header().Get("Allow"))
+}
+
+func TestRESTHandler_Authorization(t *testing.T) {
+	p, err := newPrincipal("alice", []string{soccerSport}, "")
+	require.NoError(t, err)
+
+	handler := initRESTHandler(t, authenticators{&staticKeys{
+		tokenToPrincipal:   map[string]*principal{"alice-token": p},
+		subjectToPrincipal: nil,
+	}})
+
+	w := serveREST(handler, http.MethodGet, "/api/v1/lines", "")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serveREST(handler, http.MethodGet, "/api/v1/lines", "alice-token")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var lines linesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &lines))
+	require.Equal(t, []string{soccerSport}, lines.SportNames)
+
+	w = serveREST(handler, http.MethodGet, "/api/v1/lines/football", "alice-token")
+	require.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -2,13 +2,13 @@ package main
 
 import (
 	"context"
-	"io"
+	"fmt"
 	"net"
-	"reflect"
-	"sync"
+	"sort"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -24,167 +24,385 @@ var (
 	)
 	unknownSportNameError gRPCServerError = status.Error(codes.InvalidArgument, "sport name is unknown")
 	emptySportListError   gRPCServerError = status.Error(codes.InvalidArgument, "sport list can't be empty")
+	intervalError         gRPCServerError = status.Error(codes.InvalidArgument, "interval must be positive")
+	notSubscribedError    gRPCServerError = status.Error(codes.InvalidArgument, "sport is not subscribed")
+	unknownGroupError     gRPCServerError = status.Error(codes.InvalidArgument, "subscription group doesn't exist")
+	slowConsumerError     gRPCServerError = status.Error(
+		codes.ResourceExhausted,
+		"client doesn't receive lines as fast as they are sent",
+	)
+	idleStreamError gRPCServerError = status.Error(
+		codes.DeadlineExceeded,
+		"no subscription request was received within the idle timeout",
+	)
 )
 
-type sportLinesPublisherServer struct {
-	storage                    storage
-	sportNameToPullingInterval map[string]int32
+// streamConfig limits every stream, zero durations disable the corresponding limit.
+type streamConfig struct {
+	queueSize         int
+	overflowPolicy    overflowPolicy
+	heartbeatInterval time.Duration
+	idleTimeout       time.Duration
+	maxAge            time.Duration
+	limits            clientLimits
 }
 
-func sender(
-	ctx context.Context,
-	srv SportLinesService_SubscribeOnSportLinesServer,
-	storage storage,
-	senderChan <-chan map[string]struct{},
-	wg *sync.WaitGroup,
-) {
-	sportNameToPrevLine := make(map[string]float64)
-MainLoop:
-	for {
-		select {
-		case <-ctx.Done():
-			break MainLoop
-		case update := <-senderChan:
-			sportNameToLine := make(map[string]float64)
-			if update == nil {
-				sportNameToNewLine := make(map[string]float64)
-				for sportName, prevSportLine := range sportNameToPrevLine {
-					sportLine, _ := storage.Get(sportName)
-					sportNameToLine[sportName] = sportLine - prevSportLine
-					sportNameToNewLine[sportName] = sportLine
-				}
-				sportNameToPrevLine = sportNameToNewLine
-			} else {
-				for sportName := range update {
-					sportLine, _ := storage.Get(sportName)
-					sportNameToLine[sportName] = sportLine
-					sportNameToPrevLine[sportName] = sportLine
-				}
-			}
+func defaultStreamConfig() streamConfig {
+	return streamConfig{
+		queueSize:         defaultQueueSize,
+		overflowPolicy:    coalesce,
+		heartbeatInterval: defaultHeartbeatInterval,
+		idleTimeout:       defaultIdleTimeout,
+		maxAge:            0,
+		limits: clientLimits{
+			MaxStreams:    0,
+			MaxGroups:     defaultMaxGroups,
+			MaxSportNames: 0,
+			RequestRate:   0,
+			RequestBurst:  0,
+		},
+	}
+}
 
-			resp := SportLinesResponse{
-				SportNameToLine: sportNameToLine,
-			}
-			err := srv.Send(&resp)
-			if err != nil {
-				log.Info("error in gRPC Send function: ", err)
-			}
-		}
+// sportRegistry checks sport names against the configured sports and the storage on every call,
+// so that sports pulled after a stream started are accepted and configured ones can be subscribed to in advance.
+type sportRegistry struct {
+	storage              storage
+	configuredSportNames map[string]struct{}
+}
+
+func newSportRegistry(storage storage, sportNameToPullingInterval map[string]time.Duration) *sportRegistry {
+	configuredSportNames := make(map[string]struct{}, len(sportNameToPullingInterval))
+	for sportName := range sportNameToPullingInterval {
+		configuredSportNames[sportName] = struct{}{}
 	}
-	wg.Done()
-}
-
-func timer(ctx context.Context, updateChan <-chan update, senderChan chan<- map[string]struct{}, wg *sync.WaitGroup) {
-	update := <-updateChan
-	ticker := time.NewTicker(time.Second * update.duration)
-	senderChan <- update.sportNames
-MainLoop:
-	for {
-		select {
-		case <-ctx.Done():
-			break MainLoop
-		case update = <-updateChan:
-			ticker = time.NewTicker(time.Second * update.duration)
-			senderChan <- update.sportNames
-		case <-ticker.C:
-			senderChan <- nil
-		}
+
+	return &sportRegistry{
+		storage:              storage,
+		configuredSportNames: configuredSportNames,
 	}
-	wg.Done()
 }
 
-type update struct {
-	duration   time.Duration
-	sportNames map[string]struct{}
+// sortedSportNames returns the configured sports together with the ones in the storage.
+func (r *sportRegistry) sortedSportNames() []string {
+	sportNames := r.storage.GetKeys()
+	for sportName := range r.configuredSportNames {
+		sportNames[sportName] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(sportNames))
+	for sportName := range sportNames {
+		sorted = append(sorted, sportName)
+	}
+
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+func (r *sportRegistry) isKnown(ctx context.Context, sportName string) bool {
+	if _, exists := r.configuredSportNames[sportName]; exists {
+		return true
+	}
+
+	_, _, exists := r.storage.Get(ctx, sportName)
+
+	return exists
+}
+
+type sportLinesPublisherServer struct {
+	registry                   *sportRegistry
+	sportNameToPullingInterval map[string]time.Duration
+	hub                        *subscriptionHub
+	limiter                    *clientLimiter
+	config                     streamConfig
+}
+
+func newSportLinesPublisherServer(
+	storage storage,
+	sportNameToPullingInterval map[string]time.Duration,
+	lineLog *lineChangeLog,
+	config streamConfig,
+) sportLinesPublisherServer {
+	return sportLinesPublisherServer{
+		registry:                   newSportRegistry(storage, sportNameToPullingInterval),
+		sportNameToPullingInterval: sportNameToPullingInterval,
+		hub:                        newSubscriptionHub(storage, lineLog),
+		limiter:                    newClientLimiter(config.limits),
+		config:                     config,
+	}
+}
+
+// newGRPCServer puts the given options after the codec and the access log and recovery interceptors.
+func newGRPCServer(options ...grpc.ServerOption) *grpc.Server {
+	serverOptions := append([]grpc.ServerOption{grpc.ForceServerCodec(encodedResponseCodec{})}, interceptorOptions()...)
+
+	return grpc.NewServer(append(serverOptions, options...)...)
 }
 
 func (s sportLinesPublisherServer) SubscribeOnSportLines(srv SportLinesService_SubscribeOnSportLinesServer) error {
 	log.Info("started gRPC server")
 
-	ctx := srv.Context()
+	client := clientName(srv.Context())
+
+	err := s.limiter.acquireStream(client)
+	if err != nil {
+		return err
+	}
+	defer s.limiter.releaseStream(client)
+
+	activeStreams.Inc()
+	defer activeStreams.Dec()
 
-	childCtx, cancelFunc := context.WithCancel(ctx)
-	senderChan := make(chan map[string]struct{})
-	updateChan := make(chan update)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	wg.Add(1)
+	stream := newSubscriptionStream(s, srv, client)
+	defer stream.close()
 
-	go timer(childCtx, updateChan, senderChan, wg)
-	go sender(childCtx, srv, s.storage, senderChan, wg)
+	return stream.run()
+}
 
-	prevSports := make(map[string]struct{})
-	validSportNames := s.storage.GetKeys()
+// SubscribeOnSportLinesServerStream serves the request as if it was the only request of SubscribeOnSportLines.
+func (s sportLinesPublisherServer) SubscribeOnSportLinesServerStream(
+	req *SportLinesRequest,
+	srv SportLinesService_SubscribeOnSportLinesServerStreamServer,
+) error {
+	return s.SubscribeOnSportLines(newSingleRequestStream(srv, req))
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			cancelFunc()
-			wg.Wait()
+// subscription is what a stream is subscribed to after applying all of its requests.
+type subscription struct {
+	sportNames map[string]struct{}
+	interval   time.Duration
+	// intervals of the sports which aren't sent every interval
+	sportNameToInterval map[string]time.Duration
+	deliveryMode        DeliveryMode
+}
 
-			return ctx.Err()
-		default:
-		}
+func (s subscription) sportInterval(sportName string) time.Duration {
+	if interval, exists := s.sportNameToInterval[sportName]; exists {
+		return interval
+	}
+
+	return s.interval
+}
 
-		req, err := srv.Recv()
-		if err == io.EOF {
-			cancelFunc()
-			log.Info("connection with client closed due to EOF")
+// intervalToSportNames groups the subscribed sports by the interval they are sent with.
+func (s subscription) intervalToSportNames() map[time.Duration]map[string]struct{} {
+	intervalToSportNames := make(map[time.Duration]map[string]struct{}, 1)
 
-			return nil
+	for sportName := range s.sportNames {
+		interval := s.sportInterval(sportName)
+		if intervalToSportNames[interval] == nil {
+			intervalToSportNames[interval] = make(map[string]struct{})
 		}
+
+		intervalToSportNames[interval][sportName] = struct{}{}
+	}
+
+	return intervalToSportNames
+}
+
+// validateRequest checks the request against the known sports and their pulling intervals
+// and returns the subscription which results from applying it to the current one.
+func (s sportLinesPublisherServer) validateRequest(
+	ctx context.Context,
+	req *SportLinesRequest,
+	cur subscription,
+) (subscription, error) {
+	if len(req.SportNames) == 0 {
+		return subscription{}, withFieldViolation(emptySportListError, "sportNames", "at least one sport is required")
+	}
+
+	interval := requestInterval(req)
+	if req.Interval == nil && req.TimeInterval == 0 && req.Action != SportLinesRequest_REPLACE {
+		interval = cur.interval
+	}
+
+	if interval <= 0 {
+		return subscription{}, withFieldViolation(
+			intervalError,
+			requestIntervalField(req),
+			fmt.Sprintf("interval %s is not positive", interval),
+		)
+	}
+
+	if req.Action != SportLinesRequest_REMOVE {
+		err := s.validateKnownSportNames(ctx, req.SportNames)
 		if err != nil {
-			log.Errorf("error in gRPC Recv function: %v", err)
+			return subscription{}, err
+		}
+	}
 
-			continue
+	sportNames := make(map[string]struct{})
+	if req.Action != SportLinesRequest_REPLACE {
+		for sportName := range cur.sportNames {
+			sportNames[sportName] = struct{}{}
+		}
+	}
+
+	requestedSportNames := make(map[string]struct{})
+
+	for i, sportName := range req.SportNames {
+		_, isRequested := requestedSportNames[sportName]
+		_, isSubscribed := sportNames[sportName]
+
+		switch {
+		case isRequested || (req.Action == SportLinesRequest_ADD && isSubscribed):
+			return subscription{}, withFieldViolation(
+				duplicateError,
+				fmt.Sprintf("sportNames[%d]", i),
+				fmt.Sprintf("sport %s is already in the list", sportName),
+			)
+		case req.Action == SportLinesRequest_REMOVE && !isSubscribed:
+			return subscription{}, withFieldViolation(
+				notSubscribedError,
+				fmt.Sprintf("sportNames[%d]", i),
+				fmt.Sprintf("sport %s is not subscribed", sportName),
+			)
 		}
 
-		if len(req.SportNames) == 0 {
-			cancelFunc()
+		requestedSportNames[sportName] = struct{}{}
 
-			return emptySportListError
+		if req.Action == SportLinesRequest_REMOVE {
+			delete(sportNames, sportName)
+		} else {
+			sportNames[sportName] = struct{}{}
 		}
+	}
 
-		for _, sportName := range req.SportNames {
-			_, exists := validSportNames[sportName]
-			if !exists {
-				cancelFunc()
+	if len(sportNames) == 0 {
+		return subscription{}, withFieldViolation(
+			emptySportListError,
+			"sportNames",
+			"at least one sport must stay subscribed",
+		)
+	}
 
-				return unknownSportNameError
-			}
+	sportNameToInterval, err := s.validateSportIntervals(req, cur, sportNames)
+	if err != nil {
+		return subscription{}, err
+	}
 
-			pullingInterval := s.sportNameToPullingInterval[sportName]
-			if pullingInterval > req.TimeInterval {
-				cancelFunc()
+	deliveryMode := req.DeliveryMode
+	if req.Action != SportLinesRequest_REPLACE {
+		deliveryMode = cur.deliveryMode
+	}
 
-				return periodicityError
-			}
+	result := subscription{
+		sportNames:          sportNames,
+		interval:            interval,
+		sportNameToInterval: sportNameToInterval,
+		deliveryMode:        deliveryMode,
+	}
+
+	for sportName := range sportNames {
+		pullingInterval := s.sportNameToPullingInterval[sportName]
+		if pullingInterval <= result.sportInterval(sportName) {
+			continue
 		}
 
-		update := update{
-			duration:   time.Duration(req.TimeInterval),
-			sportNames: nil,
+		field := requestIntervalField(req)
+		if _, exists := sportNameToInterval[sportName]; exists {
+			field = fmt.Sprintf("sportNameToInterval[%s]", sportName)
 		}
 
-		curSports := make(map[string]struct{})
+		return subscription{}, withFieldViolation(
+			periodicityError,
+			field,
+			fmt.Sprintf("sport %s is pulled every %s, interval can't be less than that", sportName, pullingInterval),
+		)
+	}
 
-		for _, sportName := range req.SportNames {
-			_, exists := curSports[sportName]
-			if exists {
-				cancelFunc()
+	return result, nil
+}
+
+func (s sportLinesPublisherServer) validateKnownSportNames(ctx context.Context, sportNames []string) error {
+	for i, sportName := range sportNames {
+		if !s.registry.isKnown(ctx, sportName) {
+			return withFieldViolation(
+				unknownSportNameError,
+				fmt.Sprintf("sportNames[%d]", i),
+				fmt.Sprintf("sport %s is unknown", sportName),
+			)
+		}
+	}
+
+	return nil
+}
 
-				return duplicateError
+// validateSportIntervals returns the per sport intervals of the resulting subscription.
+// Intervals of the sports which stay subscribed are kept unless the request replaces the subscription.
+func (s sportLinesPublisherServer) validateSportIntervals(
+	req *SportLinesRequest,
+	cur subscription,
+	sportNames map[string]struct{},
+) (map[string]time.Duration, error) {
+	sportNameToInterval := make(map[string]time.Duration)
+
+	if req.Action != SportLinesRequest_REPLACE {
+		for sportName, interval := range cur.sportNameToInterval {
+			if _, exists := sportNames[sportName]; exists {
+				sportNameToInterval[sportName] = interval
 			}
+		}
+	}
+
+	for sportName, interval := range req.SportNameToInterval {
+		field := fmt.Sprintf("sportNameToInterval[%s]", sportName)
 
-			curSports[sportName] = struct{}{}
+		if _, exists := sportNames[sportName]; !exists {
+			return nil, withFieldViolation(
+				notSubscribedError,
+				field,
+				fmt.Sprintf("sport %s is not subscribed", sportName),
+			)
 		}
-		if !reflect.DeepEqual(curSports, prevSports) {
-			prevSports = curSports
-			update.sportNames = curSports
+
+		if interval.AsDuration() <= 0 {
+			return nil, withFieldViolation(
+				intervalError,
+				field,
+				fmt.Sprintf("interval %s is not positive", interval.AsDuration()),
+			)
 		}
 
-		updateChan <- update
+		sportNameToInterval[sportName] = interval.AsDuration()
 	}
+
+	return sportNameToInterval, nil
+}
+
+// withFieldViolation attaches google.rpc.BadRequest details naming the offending field to the error.
+func withFieldViolation(err gRPCServerError, field, description string) error {
+	st, detailsErr := status.Convert(err).WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{
+				Field:       field,
+				Description: description,
+			},
+		},
+	})
+	if detailsErr != nil {
+		return err
+	}
+
+	return st.Err()
+}
+
+// requestInterval prefers the interval duration and falls back to whole seconds for older clients.
+func requestInterval(req *SportLinesRequest) time.Duration {
+	if req.Interval != nil {
+		return req.Interval.AsDuration()
+	}
+
+	return time.Duration(req.TimeInterval) * time.Second
+}
+
+func requestIntervalField(req *SportLinesRequest) string {
+	if req.Interval != nil {
+		return "interval"
+	}
+
+	return "timeInterval"
 }
 
 func startSportLinesPublisher(s *grpc.Server, listener net.Listener, serverStarted chan struct{}) error {
@@ -11,7 +11,10 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 const (
@@ -28,7 +31,16 @@ func init() {
 	log.SetLevel(log.WarnLevel)
 }
 
-func initServer(t *testing.T, storage *mapStorage, sportNameToPullingInterval map[string]int32) string {
+func initServer(t *testing.T, storage *mapStorage, sportNameToPullingInterval map[string]time.Duration) string {
+	return initServerWith(t, newSportLinesPublisherServer(
+		storage,
+		sportNameToPullingInterval,
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	))
+}
+
+func initServerWith(t *testing.T, server sportLinesPublisherServer, options ...grpc.ServerOption) string {
 	serverAddr := "localhost:0"
 
 	listener, err := net.Listen("tcp", serverAddr)
@@ -36,11 +48,8 @@ func initServer(t *testing.T, storage *mapStorage, sportNameToPullingInterval ma
 		t.Fatal(err)
 	}
 	serverStarted := make(chan struct{})
-	s := grpc.NewServer()
-	RegisterSportLinesServiceServer(s, sportLinesPublisherServer{
-		storage:                    storage,
-		sportNameToPullingInterval: sportNameToPullingInterval,
-	})
+	s := newGRPCServer(options...)
+	RegisterSportLinesServiceServer(s, server)
 
 	go func(s *grpc.Server, listener net.Listener, serverStarted chan struct{}) {
 		err := startSportLinesPublisher(s, listener, serverStarted)
@@ -89,7 +98,7 @@ func TestGRPCServer_Simple(t *testing.T) {
 	storage := newMapStorage()
 	sportName := soccerSport
 	sportLine := 0.5
-	storage.Upload(sportName, sportLine)
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
 	serverAddr := initServer(t, storage, nil)
 	stream := initClient(t, serverAddr)
 
@@ -115,7 +124,7 @@ func TestGRPCServer_Interval(t *testing.T) {
 	storage := newMapStorage()
 	sportName := soccerSport
 	sportLine := 0.5
-	storage.Upload(sportName, sportLine)
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
 	serverAddr := initServer(t, storage, nil)
 	stream := initClient(t, serverAddr)
 
@@ -154,7 +163,7 @@ func TestGRPCServer_Deltas(t *testing.T) {
 	storage := newMapStorage()
 	sportName := soccerSport
 	sportLine := 0.5
-	storage.Upload(sportName, sportLine)
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
 	serverAddr := initServer(t, storage, nil)
 	stream := initClient(t, serverAddr)
 
@@ -174,7 +183,7 @@ func TestGRPCServer_Deltas(t *testing.T) {
 	}
 
 	delta := 0.1
-	storage.Upload(sportName, sportLine+delta)
+	storage.Upload(context.Background(), sportName, sportLine+delta, time.Now())
 
 	resp, err := stream.Recv()
 	if err != nil {
@@ -192,8 +201,8 @@ func TestGRPCServer_ManySports(t *testing.T) {
 	sportName2 := baseballSport
 	sportLine2 := 0.6
 
-	storage.Upload(sportName, sportLine)
-	storage.Upload(sportName2, sportLine2)
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
+	storage.Upload(context.Background(), sportName2, sportLine2, time.Now())
 	serverAddr := initServer(t, storage, nil)
 	stream := initClient(t, serverAddr)
 
@@ -219,7 +228,7 @@ func TestGRPCServer_IntervalChange(t *testing.T) {
 	storage := newMapStorage()
 	sportName := soccerSport
 	sportLine := 0.5
-	storage.Upload(sportName, sportLine)
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
 	serverAddr := initServer(t, storage, nil)
 	stream := initClient(t, serverAddr)
 
@@ -281,8 +290,8 @@ func TestGRPCServer_NoDeltasAfterSportChanges(t *testing.T) {
 	sportName2 := baseballSport
 	sportLine2 := 0.6
 
-	storage.Upload(sportName, sportLine)
-	storage.Upload(sportName2, sportLine2)
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
+	storage.Upload(context.Background(), sportName2, sportLine2, time.Now())
 	serverAddr := initServer(t, storage, nil)
 	stream := initClient(t, serverAddr)
 
@@ -328,8 +337,8 @@ func TestGRPCServer_ManySubscribers(t *testing.T) {
 	sportName2 := baseballSport
 	sportLine2 := 0.6
 
-	storage.Upload(sportName, sportLine)
-	storage.Upload(sportName2, sportLine2)
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
+	storage.Upload(context.Background(), sportName2, sportLine2, time.Now())
 	serverAddr := initServer(t, storage, nil)
 
 	clientFunc := func(
@@ -391,7 +400,7 @@ func TestGRPCServer_DeltasIfSportListDidntChange(t *testing.T) {
 	storage := newMapStorage()
 	sportName := soccerSport
 	sportLine := 0.5
-	storage.Upload(sportName, sportLine)
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
 	serverAddr := initServer(t, storage, nil)
 	stream := initClient(t, serverAddr)
 
@@ -412,7 +421,7 @@ func TestGRPCServer_DeltasIfSportListDidntChange(t *testing.T) {
 	}
 
 	delta := 0.25
-	storage.Upload(sportName, sportLine+delta)
+	storage.Upload(context.Background(), sportName, sportLine+delta, time.Now())
 	req = &SportLinesRequest{
 		SportNames:   []string{sportName},
 		TimeInterval: timeInterval + 1,
@@ -474,8 +483,8 @@ func TestGRPCServer_EmptySportNamesList(t *testing.T) {
 
 func TestGRPCServer_SportNamesDuplicates(t *testing.T) {
 	storage := newMapStorage()
-	storage.Upload(footballSport, 0.1)
-	storage.Upload(soccerSport, 0.2)
+	storage.Upload(context.Background(), footballSport, 0.1, time.Now())
+	storage.Upload(context.Background(), soccerSport, 0.2, time.Now())
 	serverAddr := initServer(t, storage, nil)
 	stream := initClient(t, serverAddr)
 
@@ -496,8 +505,8 @@ func TestGRPCServer_SportNamesDuplicates(t *testing.T) {
 
 func TestGRPCServer_IntervalLessThanStorageUpdate(t *testing.T) {
 	storage := newMapStorage()
-	storage.Upload(footballSport, 0.1)
-	serverAddr := initServer(t, storage, map[string]int32{footballSport: 2})
+	storage.Upload(context.Background(), footballSport, 0.1, time.Now())
+	serverAddr := initServer(t, storage, map[string]time.Duration{footballSport: 2 * time.Second})
 	stream := initClient(t, serverAddr)
 
 	req := &SportLinesRequest{
@@ -514,3 +523,570 @@ func TestGRPCServer_IntervalLessThanStorageUpdate(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, periodicityError.Error(), err.Error())
 }
+
+func TestGRPCServer_Resume(t *testing.T) {
+	storage := newMapStorage()
+	sportName := soccerSport
+	sportLine := 0.5
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
+	serverAddr := initServer(t, storage, nil)
+	stream := initClient(t, serverAddr)
+
+	req := &SportLinesRequest{
+		SportNames:   []string{sportName},
+		TimeInterval: 1,
+	}
+
+	err := stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	require.Equal(t, SportLinesResponse_SNAPSHOT, resp.Kind)
+
+	err = stream.CloseSend()
+	require.NoError(t, err)
+
+	delta := 0.2
+	storage.Upload(context.Background(), sportName, sportLine+delta, time.Now())
+
+	stream = initClient(t, serverAddr)
+	req = &SportLinesRequest{
+		SportNames:   []string{sportName},
+		TimeInterval: 1,
+		ResumeFrom:   resp.Sequence,
+	}
+
+	err = stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	resumedResp, err := stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	require.Equal(t, SportLinesResponse_DELTA, resumedResp.Kind)
+	require.Greater(t, resumedResp.Sequence, resp.Sequence)
+	require.Equal(t, 1, len(resumedResp.SportNameToLine))
+	require.LessOrEqual(t, math.Abs(delta-resumedResp.SportNameToLine[sportName]), eps)
+}
+
+func TestGRPCServer_ResumeGap(t *testing.T) {
+	storage := newMapStorage()
+	sportName := soccerSport
+	sportLine := 0.5
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
+	serverAddr := initServer(t, storage, nil)
+	stream := initClient(t, serverAddr)
+
+	req := &SportLinesRequest{
+		SportNames:   []string{sportName},
+		TimeInterval: 1,
+		ResumeFrom:   1,
+	}
+
+	err := stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	require.Equal(t, SportLinesResponse_GAP_SNAPSHOT, resp.Kind)
+	require.Equal(t, map[string]float64{sportName: sportLine}, resp.SportNameToLine)
+}
+
+func TestGRPCServer_SubSecondInterval(t *testing.T) {
+	storage := newMapStorage()
+	sportName := soccerSport
+	sportLine := 0.5
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
+	serverAddr := initServer(t, storage, map[string]time.Duration{sportName: 100 * time.Millisecond})
+	stream := initClient(t, serverAddr)
+
+	interval := 200 * time.Millisecond
+	req := &SportLinesRequest{
+		SportNames: []string{sportName},
+		Interval:   durationpb.New(interval),
+	}
+
+	err := stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	_, err = stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	start := time.Now()
+
+	_, err = stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	require.Less(t, time.Since(start).Seconds(), 1.0)
+}
+
+func TestGRPCServer_NonPositiveInterval(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), footballSport, 0.1, time.Now())
+	serverAddr := initServer(t, storage, nil)
+	stream := initClient(t, serverAddr)
+
+	req := &SportLinesRequest{
+		SportNames: []string{footballSport},
+	}
+
+	err := stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+	_, err = stream.Recv()
+
+	require.Error(t, err)
+	require.Equal(t, intervalError.Error(), err.Error())
+}
+
+func TestGRPCServer_InvalidUpdateKeepsSubscription(t *testing.T) {
+	storage := newMapStorage()
+	sportName := soccerSport
+	sportLine := 0.5
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
+	serverAddr := initServer(t, storage, nil)
+	stream := initClient(t, serverAddr)
+
+	req := &SportLinesRequest{
+		SportNames:   []string{sportName},
+		TimeInterval: 1,
+	}
+
+	err := stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	_, err = stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	req = &SportLinesRequest{
+		SportNames:   []string{"tennis"},
+		TimeInterval: 1,
+	}
+
+	err = stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	require.Equal(t, SportLinesResponse_ERROR, resp.Kind)
+	require.Equal(t, unknownSportNameError.Error(), status.ErrorProto(resp.Error).Error())
+
+	resp, err = stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	require.Equal(t, SportLinesResponse_DELTA, resp.Kind)
+	require.Equal(t, map[string]float64{sportName: 0}, resp.SportNameToLine)
+}
+
+func TestGRPCServer_BadRequestDetails(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), footballSport, 0.1, time.Now())
+	serverAddr := initServer(t, storage, map[string]time.Duration{footballSport: 2 * time.Second})
+	stream := initClient(t, serverAddr)
+
+	req := &SportLinesRequest{
+		SportNames:   []string{footballSport},
+		TimeInterval: 1,
+	}
+
+	err := stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+	_, err = stream.Recv()
+
+	require.Error(t, err)
+
+	details := status.Convert(err).Details()
+	require.Equal(t, 1, len(details))
+
+	badRequest, ok := details[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Equal(t, 1, len(badRequest.FieldViolations))
+	require.Equal(t, "timeInterval", badRequest.FieldViolations[0].Field)
+	require.Contains(t, badRequest.FieldViolations[0].Description, footballSport)
+	require.Contains(t, badRequest.FieldViolations[0].Description, "2s")
+}
+
+func TestGRPCServer_SportPulledAfterStreamStart(t *testing.T) {
+	storage := newMapStorage()
+	serverAddr := initServer(t, storage, nil)
+	stream := initClient(t, serverAddr)
+
+	sportName := soccerSport
+	sportLine := 0.5
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
+
+	req := &SportLinesRequest{
+		SportNames:   []string{sportName},
+		TimeInterval: 1,
+	}
+
+	err := stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	require.Equal(t, map[string]float64{sportName: sportLine}, resp.SportNameToLine)
+}
+
+func TestGRPCServer_PendingSport(t *testing.T) {
+	storage := newMapStorage()
+	sportName := soccerSport
+	serverAddr := initServer(t, storage, map[string]time.Duration{sportName: time.Second})
+	stream := initClient(t, serverAddr)
+
+	req := &SportLinesRequest{
+		SportNames:   []string{sportName},
+		TimeInterval: 1,
+	}
+
+	err := stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	require.Equal(t, 0, len(resp.SportNameToLine))
+	require.Equal(t, []string{sportName}, resp.PendingSportNames)
+
+	sportLine := 0.5
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
+
+	resp, err = stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	require.Equal(t, map[string]float64{sportName: sportLine}, resp.SportNameToLine)
+	require.Equal(t, 0, len(resp.PendingSportNames))
+}
+
+func TestGRPCServer_AddAndRemoveSports(t *testing.T) {
+	storage := newMapStorage()
+	sportName := soccerSport
+	sportLine := 0.5
+	sportName2 := baseballSport
+	sportLine2 := 0.6
+
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
+	storage.Upload(context.Background(), sportName2, sportLine2, time.Now())
+	serverAddr := initServer(t, storage, nil)
+	stream := initClient(t, serverAddr)
+
+	req := &SportLinesRequest{
+		SportNames:   []string{sportName},
+		TimeInterval: 1,
+	}
+
+	err := stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	_, err = stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	req = &SportLinesRequest{
+		SportNames: []string{sportName2},
+		Action:     SportLinesRequest_ADD,
+	}
+
+	err = stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	require.Equal(t, SportLinesResponse_DELTA, resp.Kind)
+	require.Equal(t, []string{sportName2}, resp.AbsoluteSportNames)
+	require.Equal(t, map[string]float64{sportName: 0, sportName2: sportLine2}, resp.SportNameToLine)
+
+	req = &SportLinesRequest{
+		SportNames: []string{sportName},
+		Action:     SportLinesRequest_REMOVE,
+	}
+
+	err = stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	resp, err = stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	require.Equal(t, SportLinesResponse_DELTA, resp.Kind)
+	require.Equal(t, 0, len(resp.AbsoluteSportNames))
+	require.Equal(t, map[string]float64{sportName2: 0}, resp.SportNameToLine)
+
+	err = stream.Send(req)
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	resp, err = stream.Recv()
+	if err != nil {
+		t.Fatal("client was unable to receive response, err:", err)
+	}
+
+	require.Equal(t, SportLinesResponse_ERROR, resp.Kind)
+	require.Equal(t, notSubscribedError.Error(), status.ErrorProto(resp.Error).Error())
+}
+
+func TestGRPCServer_SubscriptionGroups(t *testing.T) {
+	storage := newMapStorage()
+	sportName := soccerSport
+	sportLine := 0.5
+	sportName2 := baseballSport
+	sportLine2 := 0.6
+
+	storage.Upload(context.Background(), sportName, sportLine, time.Now())
+	storage.Upload(context.Background(), sportName2, sportLine2, time.Now())
+	serverAddr := initServer(t, storage, nil)
+	stream := initClient(t, serverAddr)
+
+	requests := []*SportLinesRequest{
+		{
+			SportNames:   []string{sportName},
+			TimeInterval: 1,
+			GroupId:      "live",
+		},
+		{
+			SportNames:   []string{sportName2},
+			TimeInterval: 2,
+			GroupId:      "outrights",
+			DeliveryMode: DeliveryMode_ABSOLUTE,
+		},
+	}
+
+	for _, req := range requests {
+		err := stream.Send(req)
+		if err != nil {
+			t.Fatal("client was unable to send request, err:", err)
+		}
+
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatal("client was unable to receive response, err:", err)
+		}
+
+		require.Equal(t, req.GroupId, resp.GroupId)
+		require.Equal(t, SportLinesResponse_SNAPSHOT, resp.Kind)
+		require.Equal(t, 1, len(resp.SportNameToLine))
+	}
+
+	groupIDToResp := make(map[string]*SportLinesResponse)
+	for len(groupIDToResp) != 2 {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatal("client was unable to receive response, err:", err)
+		}
+
+		groupIDToResp[resp.GroupId] = resp
+	}
+
+	require.Equal(t, SportLinesResponse_DELTA, groupIDToResp["live"].Kind)
+	require.Equal(t, map[string]float64{sportName: 0}, groupIDToResp["live"].SportNameToLine)
+	require.Equal(t, SportLinesResponse_SNAPSHOT, groupIDToResp["outrights"].Kind)
+	require.Equal(t, map[string]float64{sportName2: sportLine2}, groupIDToResp["outrights"].SportNameToLine)
+
+	err := stream.Send(&SportLinesRequest{
+		GroupId: "closed",
+		Action:  SportLinesRequest_CLOSE,
+	})
+	if err != nil {
+		t.Fatal("client was unable to send request, err:", err)
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatal("client was unable to receive response, err:", err)
+		}
+
+		if resp.Kind == SportLinesResponse_ERROR {
+			require.Equal(t, "closed", resp.GroupId)
+			require.Equal(t, unknownGroupError.Error(), status.ErrorProto(resp.Error).Error())
+
+			break
+		}
+	}
+}
+
+func TestGRPCServer_SportIntervals(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	storage.Upload(context.Background(), footballSport, 0.5, time.Now())
+	serverAddr := initServer(t, storage, map[string]time.Duration{
+		soccerSport:   100 * time.Millisecond,
+		footballSport: time.Second,
+	})
+	stream := initClient(t, serverAddr)
+
+	err := stream.Send(&SportLinesRequest{
+		SportNames:          []string{soccerSport, footballSport},
+		Interval:            durationpb.New(200 * time.Millisecond),
+		SportNameToInterval: map[string]*durationpb.Duration{footballSport: durationpb.New(time.Second)},
+	})
+	require.NoError(t, err)
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, map[string]float64{soccerSport: 0.5, footballSport: 0.5}, resp.SportNameToLine)
+
+	// the fifth soccer tick falls on the first football one, so they come in one response
+	for i := 0; i != 5; i++ {
+		resp, err = stream.Recv()
+		require.NoError(t, err)
+		require.Contains(t, resp.SportNameToLine, soccerSport)
+		require.Equal(t, i == 4, len(resp.SportNameToLine) == 2)
+	}
+}
+
+func TestGRPCServer_SportIntervalLessThanStorageUpdate(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	storage.Upload(context.Background(), footballSport, 0.5, time.Now())
+	serverAddr := initServer(t, storage, map[string]time.Duration{footballSport: 2 * time.Second})
+	stream := initClient(t, serverAddr)
+
+	err := stream.Send(&SportLinesRequest{
+		SportNames:          []string{soccerSport, footballSport},
+		TimeInterval:        3,
+		SportNameToInterval: map[string]*durationpb.Duration{footballSport: durationpb.New(time.Second)},
+	})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	st := status.Convert(err)
+	require.Equal(t, periodicityError.Error(), st.Err().Error())
+
+	require.Equal(t, 1, len(st.Details()))
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Equal(t, "sportNameToInterval[football]", badRequest.FieldViolations[0].Field)
+}
+
+func TestGRPCServer_Resync(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	serverAddr := initServer(t, storage, nil)
+	stream := initClient(t, serverAddr)
+
+	err := stream.Send(&SportLinesRequest{
+		SportNames:   []string{soccerSport},
+		TimeInterval: 1,
+	})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	storage.Upload(context.Background(), soccerSport, 0.75, time.Now())
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, SportLinesResponse_DELTA, resp.Kind)
+	require.Equal(t, map[string]float64{soccerSport: 0.25}, resp.SportNameToLine)
+
+	err = stream.Send(&SportLinesRequest{Action: SportLinesRequest_RESYNC})
+	require.NoError(t, err)
+
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, SportLinesResponse_SNAPSHOT, resp.Kind)
+	require.Equal(t, map[string]float64{soccerSport: 0.75}, resp.SportNameToLine)
+
+	err = stream.Send(&SportLinesRequest{Action: SportLinesRequest_RESYNC, GroupId: "unknown"})
+	require.NoError(t, err)
+
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, SportLinesResponse_ERROR, resp.Kind)
+	require.Equal(t, unknownGroupError.Error(), status.FromProto(resp.Error).Err().Error())
+
+	start := time.Now()
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, SportLinesResponse_DELTA, resp.Kind)
+	// the tick after the resync comes on schedule, give it some leeway for timer delays
+	require.Less(t, time.Since(start).Seconds(), 1.5)
+}
+
+func TestGRPCServer_PullTime(t *testing.T) {
+	storage := newMapStorage()
+	pulledAt := time.Now().Add(-time.Second)
+	storage.Upload(context.Background(), soccerSport, 0.5, pulledAt)
+	storage.Upload(context.Background(), footballSport, 0.25, pulledAt)
+	serverAddr := initServer(t, storage, nil)
+	stream := initClient(t, serverAddr)
+
+	err := stream.Send(&SportLinesRequest{
+		SportNames:   []string{soccerSport, footballSport},
+		TimeInterval: 1,
+	})
+	require.NoError(t, err)
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Len(t, resp.SportNameToPullTime, 2)
+	require.True(t, pulledAt.Equal(resp.SportNameToPullTime[soccerSport].AsTime()))
+
+	nextPulledAt := time.Now()
+	storage.Upload(context.Background(), soccerSport, 0.75, nextPulledAt)
+
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, map[string]float64{soccerSport: 0.25, footballSport: 0}, resp.SportNameToLine)
+	require.True(t, nextPulledAt.Equal(resp.SportNameToPullTime[soccerSport].AsTime()))
+	require.True(t, pulledAt.Equal(resp.SportNameToPullTime[footballSport].AsTime()))
+}
@@ -12,11 +12,14 @@ import (
 	sync "sync"
 
 	proto "github.com/golang/protobuf/proto"
+	status "google.golang.org/genproto/googleapis/rpc/status"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
-	status "google.golang.org/grpc/status"
+	status1 "google.golang.org/grpc/status"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
@@ -30,6 +33,181 @@ const (
 // of the legacy proto package is being used.
 const _ = proto.ProtoPackageIsVersion4
 
+type DeliveryMode int32
+
+const (
+	// deltas of all subscribed sports on every tick
+	DeliveryMode_DELTAS DeliveryMode = 0
+	// deltas of the changed sports only, nothing is sent if no sport changed
+	DeliveryMode_CHANGED_ONLY DeliveryMode = 1
+	// absolute lines of all sports due on every tick
+	DeliveryMode_ABSOLUTE DeliveryMode = 2
+)
+
+// Enum value maps for DeliveryMode.
+var (
+	DeliveryMode_name = map[int32]string{
+		0: "DELTAS",
+		1: "CHANGED_ONLY",
+		2: "ABSOLUTE",
+	}
+	DeliveryMode_value = map[string]int32{
+		"DELTAS":       0,
+		"CHANGED_ONLY": 1,
+		"ABSOLUTE":     2,
+	}
+)
+
+func (x DeliveryMode) Enum() *DeliveryMode {
+	p := new(DeliveryMode)
+	*p = x
+	return p
+}
+
+func (x DeliveryMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DeliveryMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_sportlines_proto_enumTypes[0].Descriptor()
+}
+
+func (DeliveryMode) Type() protoreflect.EnumType {
+	return &file_sportlines_proto_enumTypes[0]
+}
+
+func (x DeliveryMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DeliveryMode.Descriptor instead.
+func (DeliveryMode) EnumDescriptor() ([]byte, []int) {
+	return file_sportlines_proto_rawDescGZIP(), []int{0}
+}
+
+type SportLinesRequest_Action int32
+
+const (
+	// sportNames is the whole new sport list
+	SportLinesRequest_REPLACE SportLinesRequest_Action = 0
+	// sportNames are added to the current list, only they get absolute lines
+	SportLinesRequest_ADD SportLinesRequest_Action = 1
+	// sportNames are removed from the current list
+	SportLinesRequest_REMOVE SportLinesRequest_Action = 2
+	// closes the group, the other fields are ignored
+	SportLinesRequest_CLOSE SportLinesRequest_Action = 3
+	// sends a snapshot of the group, the other fields are ignored
+	SportLinesRequest_RESYNC SportLinesRequest_Action = 4
+)
+
+// Enum value maps for SportLinesRequest_Action.
+var (
+	SportLinesRequest_Action_name = map[int32]string{
+		0: "REPLACE",
+		1: "ADD",
+		2: "REMOVE",
+		3: "CLOSE",
+		4: "RESYNC",
+	}
+	SportLinesRequest_Action_value = map[string]int32{
+		"REPLACE": 0,
+		"ADD":     1,
+		"REMOVE":  2,
+		"CLOSE":   3,
+		"RESYNC":  4,
+	}
+)
+
+func (x SportLinesRequest_Action) Enum() *SportLinesRequest_Action {
+	p := new(SportLinesRequest_Action)
+	*p = x
+	return p
+}
+
+func (x SportLinesRequest_Action) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SportLinesRequest_Action) Descriptor() protoreflect.EnumDescriptor {
+	return file_sportlines_proto_enumTypes[1].Descriptor()
+}
+
+func (SportLinesRequest_Action) Type() protoreflect.EnumType {
+	return &file_sportlines_proto_enumTypes[1]
+}
+
+func (x SportLinesRequest_Action) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SportLinesRequest_Action.Descriptor instead.
+func (SportLinesRequest_Action) EnumDescriptor() ([]byte, []int) {
+	return file_sportlines_proto_rawDescGZIP(), []int{0, 0}
+}
+
+type SportLinesResponse_Kind int32
+
+const (
+	SportLinesResponse_SNAPSHOT SportLinesResponse_Kind = 0
+	SportLinesResponse_DELTA    SportLinesResponse_Kind = 1
+	// resuming was impossible because the replay buffer has rolled over, lines are absolute
+	SportLinesResponse_GAP_SNAPSHOT SportLinesResponse_Kind = 2
+	// request was rejected, the previous subscription is still active
+	SportLinesResponse_ERROR SportLinesResponse_Kind = 3
+	// nothing else was sent during the heartbeat interval, carries no lines
+	SportLinesResponse_HEARTBEAT SportLinesResponse_Kind = 4
+	// the stream reached its maximum age and ends after this response,
+	// the client should reconnect resuming every group from the last sequence it received
+	SportLinesResponse_RECONNECT SportLinesResponse_Kind = 5
+)
+
+// Enum value maps for SportLinesResponse_Kind.
+var (
+	SportLinesResponse_Kind_name = map[int32]string{
+		0: "SNAPSHOT",
+		1: "DELTA",
+		2: "GAP_SNAPSHOT",
+		3: "ERROR",
+		4: "HEARTBEAT",
+		5: "RECONNECT",
+	}
+	SportLinesResponse_Kind_value = map[string]int32{
+		"SNAPSHOT":     0,
+		"DELTA":        1,
+		"GAP_SNAPSHOT": 2,
+		"ERROR":        3,
+		"HEARTBEAT":    4,
+		"RECONNECT":    5,
+	}
+)
+
+func (x SportLinesResponse_Kind) Enum() *SportLinesResponse_Kind {
+	p := new(SportLinesResponse_Kind)
+	*p = x
+	return p
+}
+
+func (x SportLinesResponse_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SportLinesResponse_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_sportlines_proto_enumTypes[2].Descriptor()
+}
+
+func (SportLinesResponse_Kind) Type() protoreflect.EnumType {
+	return &file_sportlines_proto_enumTypes[2]
+}
+
+func (x SportLinesResponse_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SportLinesResponse_Kind.Descriptor instead.
+func (SportLinesResponse_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_sportlines_proto_rawDescGZIP(), []int{1, 0}
+}
+
 type SportLinesRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -37,6 +215,19 @@ type SportLinesRequest struct {
 
 	SportNames   []string `protobuf:"bytes,1,rep,name=sportNames,proto3" json:"sportNames,omitempty"`
 	TimeInterval int32    `protobuf:"varint,2,opt,name=timeInterval,proto3" json:"timeInterval,omitempty"`
+	// sequence of the last response received before reconnecting, only used in the first request of a stream
+	ResumeFrom uint64 `protobuf:"varint,3,opt,name=resumeFrom,proto3" json:"resumeFrom,omitempty"`
+	// takes precedence over timeInterval which is kept for clients using whole seconds
+	Interval *durationpb.Duration `protobuf:"bytes,4,opt,name=interval,proto3" json:"interval,omitempty"`
+	// for ADD and REMOVE the interval may be omitted to keep the current one
+	Action SportLinesRequest_Action `protobuf:"varint,5,opt,name=action,proto3,enum=protobuf.SportLinesRequest_Action" json:"action,omitempty"`
+	// every group of a stream has its own sports, interval and delivery mode
+	GroupId string `protobuf:"bytes,6,opt,name=groupId,proto3" json:"groupId,omitempty"`
+	// ADD and REMOVE keep the current delivery mode
+	DeliveryMode DeliveryMode `protobuf:"varint,7,opt,name=deliveryMode,proto3,enum=protobuf.DeliveryMode" json:"deliveryMode,omitempty"`
+	// intervals of single sports, the other sports are sent every interval,
+	// ADD and REMOVE keep the intervals of the sports which stay subscribed
+	SportNameToInterval map[string]*durationpb.Duration `protobuf:"bytes,8,rep,name=sportNameToInterval,proto3" json:"sportNameToInterval,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 }
 
 func (x *SportLinesRequest) Reset() {
@@ -85,12 +276,64 @@ func (x *SportLinesRequest) GetTimeInterval() int32 {
 	return 0
 }
 
+func (x *SportLinesRequest) GetResumeFrom() uint64 {
+	if x != nil {
+		return x.ResumeFrom
+	}
+	return 0
+}
+
+func (x *SportLinesRequest) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+func (x *SportLinesRequest) GetAction() SportLinesRequest_Action {
+	if x != nil {
+		return x.Action
+	}
+	return SportLinesRequest_REPLACE
+}
+
+func (x *SportLinesRequest) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *SportLinesRequest) GetDeliveryMode() DeliveryMode {
+	if x != nil {
+		return x.DeliveryMode
+	}
+	return DeliveryMode_DELTAS
+}
+
+func (x *SportLinesRequest) GetSportNameToInterval() map[string]*durationpb.Duration {
+	if x != nil {
+		return x.SportNameToInterval
+	}
+	return nil
+}
+
 type SportLinesResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	SportNameToLine map[string]float64 `protobuf:"bytes,1,rep,name=sportNameToLine,proto3" json:"sportNameToLine,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"fixed64,2,opt,name=value,proto3"`
+	SportNameToLine map[string]float64      `protobuf:"bytes,1,rep,name=sportNameToLine,proto3" json:"sportNameToLine,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"fixed64,2,opt,name=value,proto3"`
+	Sequence        uint64                  `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Kind            SportLinesResponse_Kind `protobuf:"varint,3,opt,name=kind,proto3,enum=protobuf.SportLinesResponse_Kind" json:"kind,omitempty"`
+	Error           *status.Status          `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	// subscribed sports which weren't pulled yet, their lines start from zero
+	PendingSportNames []string `protobuf:"bytes,5,rep,name=pendingSportNames,proto3" json:"pendingSportNames,omitempty"`
+	// sports in a DELTA response whose lines are absolute because they were just added
+	AbsoluteSportNames []string `protobuf:"bytes,6,rep,name=absoluteSportNames,proto3" json:"absoluteSportNames,omitempty"`
+	GroupId            string   `protobuf:"bytes,7,opt,name=groupId,proto3" json:"groupId,omitempty"`
+	// when the lines of the response were pulled from the lines provider, so that clients can measure their lag
+	SportNameToPullTime map[string]*timestamppb.Timestamp `protobuf:"bytes,8,rep,name=sportNameToPullTime,proto3" json:"sportNameToPullTime,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 }
 
 func (x *SportLinesResponse) Reset() {
@@ -132,36 +375,166 @@ func (x *SportLinesResponse) GetSportNameToLine() map[string]float64 {
 	return nil
 }
 
+func (x *SportLinesResponse) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *SportLinesResponse) GetKind() SportLinesResponse_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return SportLinesResponse_SNAPSHOT
+}
+
+func (x *SportLinesResponse) GetError() *status.Status {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+func (x *SportLinesResponse) GetPendingSportNames() []string {
+	if x != nil {
+		return x.PendingSportNames
+	}
+	return nil
+}
+
+func (x *SportLinesResponse) GetAbsoluteSportNames() []string {
+	if x != nil {
+		return x.AbsoluteSportNames
+	}
+	return nil
+}
+
+func (x *SportLinesResponse) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *SportLinesResponse) GetSportNameToPullTime() map[string]*timestamppb.Timestamp {
+	if x != nil {
+		return x.SportNameToPullTime
+	}
+	return nil
+}
+
 var File_sportlines_proto protoreflect.FileDescriptor
 
 var file_sportlines_proto_rawDesc = []byte{
 	0x0a, 0x10, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x12, 0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x22, 0x57, 0x0a, 0x11,
-	0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65,
-	0x73, 0x12, 0x22, 0x0a, 0x0c, 0x74, 0x69, 0x6d, 0x65, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61,
-	0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x74, 0x69, 0x6d, 0x65, 0x49, 0x6e, 0x74,
-	0x65, 0x72, 0x76, 0x61, 0x6c, 0x22, 0xb5, 0x01, 0x0a, 0x12, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c,
-	0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x5b, 0x0a, 0x0f,
-	0x73, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54, 0x6f, 0x4c, 0x69, 0x6e, 0x65, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x31, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x74, 0x6f, 0x12, 0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x1a, 0x1e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x75,
+	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x17, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x72, 0x70, 0x63, 0x2f, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xce, 0x04, 0x0a, 0x11, 0x53, 0x70, 0x6f, 0x72, 0x74,
+	0x4c, 0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1e, 0x0a, 0x0a,
+	0x73, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x0a, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x12, 0x22, 0x0a, 0x0c,
+	0x74, 0x69, 0x6d, 0x65, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x0c, 0x74, 0x69, 0x6d, 0x65, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c,
+	0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x46, 0x72, 0x6f, 0x6d, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x46, 0x72, 0x6f, 0x6d,
+	0x12, 0x35, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x69,
+	0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x3a, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x22, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x2e, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x49, 0x64, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x49, 0x64, 0x12, 0x3a, 0x0a,
+	0x0c, 0x64, 0x65, 0x6c, 0x69, 0x76, 0x65, 0x72, 0x79, 0x4d, 0x6f, 0x64, 0x65, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44,
+	0x65, 0x6c, 0x69, 0x76, 0x65, 0x72, 0x79, 0x4d, 0x6f, 0x64, 0x65, 0x52, 0x0c, 0x64, 0x65, 0x6c,
+	0x69, 0x76, 0x65, 0x72, 0x79, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x66, 0x0a, 0x13, 0x73, 0x70, 0x6f,
+	0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54, 0x6f, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c,
+	0x18, 0x08, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x34, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54, 0x6f, 0x49,
+	0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x13, 0x73, 0x70,
+	0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54, 0x6f, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61,
+	0x6c, 0x1a, 0x61, 0x0a, 0x18, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54, 0x6f,
+	0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a,
+	0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12,
+	0x2f, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x22, 0x41, 0x0a, 0x06, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x0b,
+	0x0a, 0x07, 0x52, 0x45, 0x50, 0x4c, 0x41, 0x43, 0x45, 0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x41,
+	0x44, 0x44, 0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x52, 0x45, 0x4d, 0x4f, 0x56, 0x45, 0x10, 0x02,
+	0x12, 0x09, 0x0a, 0x05, 0x43, 0x4c, 0x4f, 0x53, 0x45, 0x10, 0x03, 0x12, 0x0a, 0x0a, 0x06, 0x52,
+	0x45, 0x53, 0x59, 0x4e, 0x43, 0x10, 0x04, 0x22, 0xd3, 0x05, 0x0a, 0x12, 0x53, 0x70, 0x6f, 0x72,
+	0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x5b,
+	0x0a, 0x0f, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54, 0x6f, 0x4c, 0x69, 0x6e,
+	0x65, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x31, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54,
+	0x6f, 0x4c, 0x69, 0x6e, 0x65, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0f, 0x73, 0x70, 0x6f, 0x72,
+	0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54, 0x6f, 0x4c, 0x69, 0x6e, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x73,
+	0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73,
+	0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x35, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x21, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
 	0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54, 0x6f, 0x4c,
-	0x69, 0x6e, 0x65, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0f, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x4e,
-	0x61, 0x6d, 0x65, 0x54, 0x6f, 0x4c, 0x69, 0x6e, 0x65, 0x1a, 0x42, 0x0a, 0x14, 0x53, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x2e, 0x4b, 0x69, 0x6e, 0x64, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x28,
+	0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x2c, 0x0a, 0x11, 0x70, 0x65, 0x6e, 0x64,
+	0x69, 0x6e, 0x67, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x05, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x11, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x53, 0x70, 0x6f, 0x72,
+	0x74, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x12, 0x2e, 0x0a, 0x12, 0x61, 0x62, 0x73, 0x6f, 0x6c, 0x75,
+	0x74, 0x65, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x12, 0x61, 0x62, 0x73, 0x6f, 0x6c, 0x75, 0x74, 0x65, 0x53, 0x70, 0x6f, 0x72,
+	0x74, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x49,
+	0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x49, 0x64,
+	0x12, 0x67, 0x0a, 0x13, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54, 0x6f, 0x50,
+	0x75, 0x6c, 0x6c, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x35, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69,
+	0x6e, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x53, 0x70, 0x6f, 0x72,
+	0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54, 0x6f, 0x50, 0x75, 0x6c, 0x6c, 0x54, 0x69, 0x6d, 0x65, 0x45,
+	0x6e, 0x74, 0x72, 0x79, 0x52, 0x13, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54,
+	0x6f, 0x50, 0x75, 0x6c, 0x6c, 0x54, 0x69, 0x6d, 0x65, 0x1a, 0x42, 0x0a, 0x14, 0x53, 0x70, 0x6f,
 	0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54, 0x6f, 0x4c, 0x69, 0x6e, 0x65, 0x45, 0x6e, 0x74, 0x72,
 	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
 	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x01, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x32, 0x6d, 0x0a,
-	0x11, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69,
-	0x63, 0x65, 0x12, 0x58, 0x0a, 0x15, 0x73, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x4f,
-	0x6e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x12, 0x1b, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65,
-	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x28, 0x01, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x62, 0x0a,
+	0x18, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x54, 0x6f, 0x50, 0x75, 0x6c, 0x6c,
+	0x54, 0x69, 0x6d, 0x65, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x30, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
+	0x01, 0x22, 0x5a, 0x0a, 0x04, 0x4b, 0x69, 0x6e, 0x64, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x4e, 0x41,
+	0x50, 0x53, 0x48, 0x4f, 0x54, 0x10, 0x00, 0x12, 0x09, 0x0a, 0x05, 0x44, 0x45, 0x4c, 0x54, 0x41,
+	0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x47, 0x41, 0x50, 0x5f, 0x53, 0x4e, 0x41, 0x50, 0x53, 0x48,
+	0x4f, 0x54, 0x10, 0x02, 0x12, 0x09, 0x0a, 0x05, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x03, 0x12,
+	0x0d, 0x0a, 0x09, 0x48, 0x45, 0x41, 0x52, 0x54, 0x42, 0x45, 0x41, 0x54, 0x10, 0x04, 0x12, 0x0d,
+	0x0a, 0x09, 0x52, 0x45, 0x43, 0x4f, 0x4e, 0x4e, 0x45, 0x43, 0x54, 0x10, 0x05, 0x2a, 0x3a, 0x0a,
+	0x0c, 0x44, 0x65, 0x6c, 0x69, 0x76, 0x65, 0x72, 0x79, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x0a, 0x0a,
+	0x06, 0x44, 0x45, 0x4c, 0x54, 0x41, 0x53, 0x10, 0x00, 0x12, 0x10, 0x0a, 0x0c, 0x43, 0x48, 0x41,
+	0x4e, 0x47, 0x45, 0x44, 0x5f, 0x4f, 0x4e, 0x4c, 0x59, 0x10, 0x01, 0x12, 0x0c, 0x0a, 0x08, 0x41,
+	0x42, 0x53, 0x4f, 0x4c, 0x55, 0x54, 0x45, 0x10, 0x02, 0x32, 0xd1, 0x01, 0x0a, 0x11, 0x53, 0x70,
+	0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12,
+	0x58, 0x0a, 0x15, 0x73, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x4f, 0x6e, 0x53, 0x70,
+	0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x12, 0x1b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 	0x62, 0x75, 0x66, 0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x28, 0x01, 0x30, 0x01, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x00, 0x28, 0x01, 0x30, 0x01, 0x12, 0x62, 0x0a, 0x21, 0x73, 0x75, 0x62,
+	0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x4f, 0x6e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e,
+	0x65, 0x73, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x1b,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c,
+	0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x70, 0x6f, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x30, 0x01, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -177,23 +550,43 @@ func file_sportlines_proto_rawDescGZIP() []byte {
 }
 
 var (
-	file_sportlines_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
-	file_sportlines_proto_goTypes  = []interface{}{
-		(*SportLinesRequest)(nil),  // 0: protobuf.SportLinesRequest
-		(*SportLinesResponse)(nil), // 1: protobuf.SportLinesResponse
-		nil,                        // 2: protobuf.SportLinesResponse.SportNameToLineEntry
+	file_sportlines_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+	file_sportlines_proto_msgTypes  = make([]protoimpl.MessageInfo, 5)
+	file_sportlines_proto_goTypes   = []interface{}{
+		(DeliveryMode)(0),             // 0: protobuf.DeliveryMode
+		(SportLinesRequest_Action)(0), // 1: protobuf.SportLinesRequest.Action
+		(SportLinesResponse_Kind)(0),  // 2: protobuf.SportLinesResponse.Kind
+		(*SportLinesRequest)(nil),     // 3: protobuf.SportLinesRequest
+		(*SportLinesResponse)(nil),    // 4: protobuf.SportLinesResponse
+		nil,                           // 5: protobuf.SportLinesRequest.SportNameToIntervalEntry
+		nil,                           // 6: protobuf.SportLinesResponse.SportNameToLineEntry
+		nil,                           // 7: protobuf.SportLinesResponse.SportNameToPullTimeEntry
+		(*durationpb.Duration)(nil),   // 8: google.protobuf.Duration
+		(*status.Status)(nil),         // 9: google.rpc.Status
+		(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
 	}
 )
 
 var file_sportlines_proto_depIdxs = []int32{
-	2, // 0: protobuf.SportLinesResponse.sportNameToLine:type_name -> protobuf.SportLinesResponse.SportNameToLineEntry
-	0, // 1: protobuf.SportLinesService.subscribeOnSportLines:input_type -> protobuf.SportLinesRequest
-	1, // 2: protobuf.SportLinesService.subscribeOnSportLines:output_type -> protobuf.SportLinesResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	8,  // 0: protobuf.SportLinesRequest.interval:type_name -> google.protobuf.Duration
+	1,  // 1: protobuf.SportLinesRequest.action:type_name -> protobuf.SportLinesRequest.Action
+	0,  // 2: protobuf.SportLinesRequest.deliveryMode:type_name -> protobuf.DeliveryMode
+	5,  // 3: protobuf.SportLinesRequest.sportNameToInterval:type_name -> protobuf.SportLinesRequest.SportNameToIntervalEntry
+	6,  // 4: protobuf.SportLinesResponse.sportNameToLine:type_name -> protobuf.SportLinesResponse.SportNameToLineEntry
+	2,  // 5: protobuf.SportLinesResponse.kind:type_name -> protobuf.SportLinesResponse.Kind
+	9,  // 6: protobuf.SportLinesResponse.error:type_name -> google.rpc.Status
+	7,  // 7: protobuf.SportLinesResponse.sportNameToPullTime:type_name -> protobuf.SportLinesResponse.SportNameToPullTimeEntry
+	8,  // 8: protobuf.SportLinesRequest.SportNameToIntervalEntry.value:type_name -> google.protobuf.Duration
+	10, // 9: protobuf.SportLinesResponse.SportNameToPullTimeEntry.value:type_name -> google.protobuf.Timestamp
+	3,  // 10: protobuf.SportLinesService.subscribeOnSportLines:input_type -> protobuf.SportLinesRequest
+	3,  // 11: protobuf.SportLinesService.subscribeOnSportLinesServerStream:input_type -> protobuf.SportLinesRequest
+	4,  // 12: protobuf.SportLinesService.subscribeOnSportLines:output_type -> protobuf.SportLinesResponse
+	4,  // 13: protobuf.SportLinesService.subscribeOnSportLinesServerStream:output_type -> protobuf.SportLinesResponse
+	12, // [12:14] is the sub-list for method output_type
+	10, // [10:12] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_sportlines_proto_init() }
@@ -232,13 +625,14 @@ func file_sportlines_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_sportlines_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   3,
+			NumEnums:      3,
+			NumMessages:   5,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_sportlines_proto_goTypes,
 		DependencyIndexes: file_sportlines_proto_depIdxs,
+		EnumInfos:         file_sportlines_proto_enumTypes,
 		MessageInfos:      file_sportlines_proto_msgTypes,
 	}.Build()
 	File_sportlines_proto = out.File
@@ -262,6 +656,8 @@ const _ = grpc.SupportPackageIsVersion6
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type SportLinesServiceClient interface {
 	SubscribeOnSportLines(ctx context.Context, opts ...grpc.CallOption) (SportLinesService_SubscribeOnSportLinesClient, error)
+	// subscribeOnSportLines with the only request for clients which can't stream requests, such as gRPC-Web in browsers
+	SubscribeOnSportLinesServerStream(ctx context.Context, in *SportLinesRequest, opts ...grpc.CallOption) (SportLinesService_SubscribeOnSportLinesServerStreamClient, error)
 }
 
 type sportLinesServiceClient struct {
@@ -303,9 +699,43 @@ func (x *sportLinesServiceSubscribeOnSportLinesClient) Recv() (*SportLinesRespon
 	return m, nil
 }
 
+func (c *sportLinesServiceClient) SubscribeOnSportLinesServerStream(ctx context.Context, in *SportLinesRequest, opts ...grpc.CallOption) (SportLinesService_SubscribeOnSportLinesServerStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_SportLinesService_serviceDesc.Streams[1], "/protobuf.SportLinesService/subscribeOnSportLinesServerStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sportLinesServiceSubscribeOnSportLinesServerStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SportLinesService_SubscribeOnSportLinesServerStreamClient interface {
+	Recv() (*SportLinesResponse, error)
+	grpc.ClientStream
+}
+
+type sportLinesServiceSubscribeOnSportLinesServerStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *sportLinesServiceSubscribeOnSportLinesServerStreamClient) Recv() (*SportLinesResponse, error) {
+	m := new(SportLinesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // SportLinesServiceServer is the server API for SportLinesService service.
 type SportLinesServiceServer interface {
 	SubscribeOnSportLines(SportLinesService_SubscribeOnSportLinesServer) error
+	// subscribeOnSportLines with the only request for clients which can't stream requests, such as gRPC-Web in browsers
+	SubscribeOnSportLinesServerStream(*SportLinesRequest, SportLinesService_SubscribeOnSportLinesServerStreamServer) error
 }
 
 // UnimplementedSportLinesServiceServer can be embedded to have forward compatible implementations.
@@ -313,7 +743,11 @@ type UnimplementedSportLinesServiceServer struct {
 }
 
 func (*UnimplementedSportLinesServiceServer) SubscribeOnSportLines(SportLinesService_SubscribeOnSportLinesServer) error {
-	return status.Errorf(codes.Unimplemented, "method SubscribeOnSportLines not implemented")
+	return status1.Errorf(codes.Unimplemented, "method SubscribeOnSportLines not implemented")
+}
+
+func (*UnimplementedSportLinesServiceServer) SubscribeOnSportLinesServerStream(*SportLinesRequest, SportLinesService_SubscribeOnSportLinesServerStreamServer) error {
+	return status1.Errorf(codes.Unimplemented, "method SubscribeOnSportLinesServerStream not implemented")
 }
 
 func RegisterSportLinesServiceServer(s *grpc.Server, srv SportLinesServiceServer) {
@@ -346,6 +780,27 @@ func (x *sportLinesServiceSubscribeOnSportLinesServer) Recv() (*SportLinesReques
 	return m, nil
 }
 
+func _SportLinesService_SubscribeOnSportLinesServerStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SportLinesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SportLinesServiceServer).SubscribeOnSportLinesServerStream(m, &sportLinesServiceSubscribeOnSportLinesServerStreamServer{stream})
+}
+
+type SportLinesService_SubscribeOnSportLinesServerStreamServer interface {
+	Send(*SportLinesResponse) error
+	grpc.ServerStream
+}
+
+type sportLinesServiceSubscribeOnSportLinesServerStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *sportLinesServiceSubscribeOnSportLinesServerStreamServer) Send(m *SportLinesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _SportLinesService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protobuf.SportLinesService",
 	HandlerType: (*SportLinesServiceServer)(nil),
@@ -357,6 +812,11 @@ var _SportLinesService_serviceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "subscribeOnSportLinesServerStream",
+			Handler:       _SportLinesService_SubscribeOnSportLinesServerStream_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "sportlines.proto",
 }
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	streamPath = "/api/v1/stream"
+	// EventSource can't set the authorization header, so the bearer token is sent in this query parameter
+	accessTokenParameter = "access_token"
+)
+
+var (
+	streamingUnsupportedError gRPCServerError = status.Error(codes.Internal, "streaming is not supported")
+	lastEventIDError          gRPCServerError = status.Error(codes.InvalidArgument, "Last-Event-ID is not a sequence")
+)
+
+// sseHandler serves SubscribeOnSportLines as Server-Sent Events. The query is the first and only request of the stream,
+// so the events are the same as the responses of a gRPC stream with this request.
+type sseHandler struct {
+	server sportLinesPublisherServer
+	auth   authenticators
+}
+
+func newSSEHandler(server sportLinesPublisherServer, auth authenticators) *sseHandler {
+	return &sseHandler{
+		server: server,
+		auth:   auth,
+	}
+}
+
+func (h *sseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+
+		return
+	}
+
+	p, err := h.auth.authenticateHTTP(r, r.URL.Query().Get(accessTokenParameter))
+	if err != nil {
+		writeJSONError(w, err)
+
+		return
+	}
+
+	req, err := sseRequest(r)
+	if err != nil {
+		writeJSONError(w, err)
+
+		return
+	}
+
+	flusher, isFlusher := w.(http.Flusher)
+	if !isFlusher {
+		writeJSONError(w, streamingUnsupportedError)
+
+		return
+	}
+
+	stream := &sseStream{
+		ctx:        httpStreamContext(r, p, sseTransport),
+		w:          w,
+		flusher:    flusher,
+		hasStarted: false,
+	}
+
+	err = h.server.SubscribeOnSportLines(newSingleRequestStream(stream, req))
+
+	switch {
+	case err == nil || status.Code(err) == codes.Canceled || r.Context().Err() != nil:
+	case !stream.hasStarted:
+		writeJSONError(w, err)
+	default:
+		// the status can't be sent once the events have started, so the client gets it as the last event
+		_ = stream.SendMsg(newErrorResponse(err))
+	}
+}
+
+// sseRequest builds the request from the query, Last-Event-ID of a reconnecting EventSource resumes the stream.
+func sseRequest(r *http.Request) (*SportLinesRequest, error) {
+	query := r.URL.Query()
+	req := &SportLinesRequest{SportNames: splitCommaSeparated(query.Get("sports"))}
+
+	if value := query.Get("interval"); value != "" {
+		interval, err := parseSecondsOrDuration(value)
+		if err != nil {
+			return nil, withFieldViolation(intervalError, "interval", err.Error())
+		}
+
+		req.Interval = durationpb.New(interval)
+	}
+
+	if value := r.Header.Get("Last-Event-ID"); value != "" {
+		resumeFrom, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, withFieldViolation(lastEventIDError, "Last-Event-ID", err.Error())
+		}
+
+		req.ResumeFrom = resumeFrom
+	}
+
+	return req, nil
+}
+
+// httpStreamContext makes the principal, the address and the trace context of an HTTP client
+// visible to the stream as they are for gRPC.
+func httpStreamContext(r *http.Request, p *principal, t transport) context.Context {
+	ctx := withTransport(r.Context(), t)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr, AuthInfo: nil})
+	}
+
+	if p != nil {
+		ctx = withPrincipal(ctx, p)
+	}
+
+	return ctx
+}
+
+// sseStream is grpc.ServerStream over an HTTP response, headers and trailers are never sent.
+type sseStream struct {
+	ctx     context.Context
+	w       http.ResponseWriter
+	flusher http.Flusher
+	// set by the first event, read once the sender has finished
+	hasStarted bool
+}
+
+func (s *sseStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *sseStream) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *sseStream) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *sseStream) SetTrailer(metadata.MD) {}
+
+// SendMsg writes the response as an event named after its kind, responses with lines carry their sequence as the id.
+func (s *sseStream) SendMsg(m interface{}) error {
+	resp := responseMessage(m)
+
+	data, err := protojson.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	if !s.hasStarted {
+		s.w.Header().Set("Content-Type", "text/event-stream")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.WriteHeader(http.StatusOK)
+		s.hasStarted = true
+	}
+
+	event := strings.Builder{}
+	if resp.Sequence != 0 {
+		fmt.Fprintf(&event, "id: %d\n", resp.Sequence)
+	}
+
+	fmt.Fprintf(&event, "event: %s\ndata: %s\n\n", strings.ToLower(resp.Kind.String()), data)
+
+	_, err = s.w.Write([]byte(event.String()))
+	if err != nil {
+		log.Info("can't write event: ", err)
+
+		return err
+	}
+
+	s.flusher.Flush()
+
+	return nil
+}
+
+// RecvMsg is never called, the request is given by singleRequestStream.
+func (s *sseStream) RecvMsg(interface{}) error {
+	return io.EOF
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type sseEvent struct {
+	id   string
+	name string
+	resp *SportLinesResponse
+}
+
+type sseClient struct {
+	t       *testing.T
+	resp    *http.Response
+	scanner *bufio.Scanner
+}
+
+func initSSEClient(t *testing.T, ctx context.Context, url, lastEventID string) *sseClient {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return &sseClient{
+		t:       t,
+		resp:    resp,
+		scanner: bufio.NewScanner(resp.Body),
+	}
+}
+
+func (c *sseClient) next() sseEvent {
+	event := sseEvent{resp: &SportLinesResponse{}}
+
+	for c.scanner.Scan() {
+		line := c.scanner.Text()
+
+		switch {
+		case line == "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(c.t, protojson.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), event.resp))
+		}
+	}
+
+	require.NoError(c.t, c.scanner.Err())
+	c.t.Fatal("event stream ended")
+
+	return event
+}
+
+func TestSSEHandler_Stream(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	storage.Upload(context.Background(), footballSport, 0.5, time.Now())
+
+	server := httptest.NewServer(newSSEHandler(newSportLinesPublisherServer(
+		storage,
+		nil,
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	), nil))
+	t.Cleanup(server.Close)
+
+	url := server.URL + streamPath + "?sports=soccer,football&interval=200ms"
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	client := initSSEClient(t, ctx, url, "")
+
+	require.Equal(t, http.StatusOK, client.resp.StatusCode)
+	require.Equal(t, "text/event-stream", client.resp.Header.Get("Content-Type"))
+
+	snapshot := client.next()
+	require.Equal(t, "snapshot", snapshot.name)
+	require.Equal(t, strconv.FormatUint(snapshot.resp.Sequence, 10), snapshot.id)
+	require.Equal(t, map[string]float64{soccerSport: 0.5, footballSport: 0.5}, snapshot.resp.SportNameToLine)
+
+	storage.Upload(context.Background(), soccerSport, 0.75, time.Now())
+
+	delta := client.next()
+	require.Equal(t, "delta", delta.name)
+	require.Equal(t, 0.25, delta.resp.SportNameToLine[soccerSport])
+
+	cancelFunc()
+	storage.Upload(context.Background(), soccerSport, 1, time.Now())
+
+	// a reconnecting EventSource sends the id of the last event it has received
+	client = initSSEClient(t, context.Background(), url, delta.id)
+
+	resumed := client.next()
+	require.Equal(t, "delta", resumed.name)
+	require.Equal(t, map[string]float64{soccerSport: 0.25, footballSport: 0}, resumed.resp.SportNameToLine)
+}
+
+func TestSSEHandler_InvalidRequest(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+
+	handler := newSSEHandler(newSportLinesPublisherServer(
+		storage,
+		map[string]time.Duration{soccerSport: 2 * time.Second},
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	), nil)
+
+	w := serveREST(handler, http.MethodGet, streamPath+"?sports=soccer&interval=1", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), `"sport soccer is pulled every 2s, interval can't be less than that"`)
+
+	w = serveREST(handler, http.MethodGet, streamPath+"?sports=soccer&interval=soon", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), `"field":"interval"`)
+}
+
+func TestSSEHandler_Authentication(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	storage.Upload(context.Background(), footballSport, 0.5, time.Now())
+
+	keys, err := loadStaticKeys(writeTestFile(t, "keys.json", `[
+		{"token": "alice-token", "principal": "alice", "sportNames": ["soccer"]}
+	]`))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(newSSEHandler(newSportLinesPublisherServer(
+		storage,
+		nil,
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	), authenticators{keys}))
+	t.Cleanup(server.Close)
+
+	for _, query := range []string{"?sports=soccer", "?sports=soccer&access_token=eve-token"} {
+		client := initSSEClient(t, context.Background(), server.URL+streamPath+query, "")
+		require.Equal(t, http.StatusUnauthorized, client.resp.StatusCode)
+	}
+
+	// EventSource can't set the authorization header, so the token is taken from the query
+	url := server.URL + streamPath + "?interval=1&access_token=alice-token"
+	client := initSSEClient(t, context.Background(), url+"&sports=football", "")
+	require.Equal(t, http.StatusForbidden, client.resp.StatusCode)
+
+	client = initSSEClient(t, context.Background(), url+"&sports=soccer", "")
+	require.Equal(t, http.StatusOK, client.resp.StatusCode)
+	require.Equal(t, "snapshot", client.next().name)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"sync"
@@ -8,6 +9,8 @@ import (
 
 	_ "github.com/go-sql-driver/mysql"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -16,36 +19,47 @@ const (
 )
 
 type storage interface {
-	Upload(key string, value float64)
-	Get(key string) (float64, bool)
+	// Upload stores the value together with the time it was pulled from the lines provider at.
+	Upload(ctx context.Context, key string, value float64, pulledAt time.Time)
+	Get(ctx context.Context, key string) (float64, time.Time, bool)
 	GetKeys() map[string]struct{}
 	Count() int
+	// Ping reports whether the storage can serve requests.
+	Ping() error
+}
+
+type pulledValue struct {
+	value    float64
+	pulledAt time.Time
 }
 
 type mapStorage struct {
 	m sync.RWMutex
-	s map[string]float64
+	s map[string]pulledValue
 }
 
 func newMapStorage() *mapStorage {
 	return &mapStorage{
 		m: sync.RWMutex{},
-		s: make(map[string]float64),
+		s: make(map[string]pulledValue),
 	}
 }
 
-func (s *mapStorage) Upload(key string, value float64) {
+func (s *mapStorage) Upload(_ context.Context, key string, value float64, pulledAt time.Time) {
 	s.m.Lock()
 	defer s.m.Unlock()
-	s.s[key] = value
+	s.s[key] = pulledValue{
+		value:    value,
+		pulledAt: pulledAt,
+	}
 }
 
-func (s *mapStorage) Get(key string) (float64, bool) {
+func (s *mapStorage) Get(_ context.Context, key string) (float64, time.Time, bool) {
 	s.m.RLock()
 	defer s.m.RUnlock()
-	value, exists := s.s[key]
+	v, exists := s.s[key]
 
-	return value, exists
+	return v.value, v.pulledAt, exists
 }
 
 func (s *mapStorage) GetKeys() map[string]struct{} {
@@ -67,6 +81,10 @@ func (s *mapStorage) Count() int {
 	return len(s.s)
 }
 
+func (s *mapStorage) Ping() error {
+	return nil
+}
+
 func initDB() *sql.DB {
 	db, err := sql.Open("mysql", DSN)
 	if err != nil {
@@ -91,6 +109,7 @@ func initDB() *sql.DB {
 		`CREATE TABLE sportlines (
 		  sport varchar(255) NOT NULL,
 		  value double precision NOT NULL,
+		  pulled_at bigint NOT NULL,
 		  PRIMARY KEY (sport)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
 	}
@@ -117,22 +136,34 @@ func newDBStorage() *dbStorage {
 	}
 }
 
-func (s *dbStorage) Upload(key string, value float64) {
-	_, exists := s.Get(key)
+// withoutCancel keeps only the span of the context. Errors of the database are fatal,
+// so a client which went away or a shutdown must not cancel its queries.
+func withoutCancel(ctx context.Context) context.Context {
+	return trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx))
+}
+
+// Upload stores the pull time as Unix nanoseconds, so that it doesn't depend on the time zone of the connection.
+func (s *dbStorage) Upload(ctx context.Context, key string, value float64, pulledAt time.Time) {
+	ctx = withoutCancel(ctx)
+	_, _, exists := s.Get(ctx, key)
 
 	var err error
 
 	if exists {
-		_, err = s.db.Exec(
-			"UPDATE sportlines SET value = ? WHERE sport = ?",
+		_, err = s.db.ExecContext(
+			ctx,
+			"UPDATE sportlines SET value = ?, pulled_at = ? WHERE sport = ?",
 			value,
+			pulledAt.UnixNano(),
 			key,
 		)
 	} else {
-		_, err = s.db.Exec(
-			"INSERT INTO sportlines (sport, value) VALUES (?, ?)",
+		_, err = s.db.ExecContext(
+			ctx,
+			"INSERT INTO sportlines (sport, value, pulled_at) VALUES (?, ?, ?)",
 			key,
 			value,
+			pulledAt.UnixNano(),
 		)
 	}
 	if err != nil {
@@ -140,20 +171,23 @@ func (s *dbStorage) Upload(key string, value float64) {
 	}
 }
 
-func (s *dbStorage) Get(key string) (float64, bool) {
-	row := s.db.QueryRow("SELECT value FROM sportlines WHERE sport = ?", key)
+func (s *dbStorage) Get(ctx context.Context, key string) (float64, time.Time, bool) {
+	row := s.db.QueryRowContext(withoutCancel(ctx), "SELECT value, pulled_at FROM sportlines WHERE sport = ?", key)
 
-	var sportLine float64
+	var (
+		sportLine float64
+		pulledAt  int64
+	)
 
-	err := row.Scan(&sportLine)
+	err := row.Scan(&sportLine, &pulledAt)
 	if errors.Is(err, sql.ErrNoRows) {
-		return 0, false
+		return 0, time.Time{}, false
 	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	return sportLine, true
+	return sportLine, time.Unix(0, pulledAt), true
 }
 
 func (s *dbStorage) GetKeys() map[string]struct{} {
@@ -194,3 +228,66 @@ func (s *dbStorage) Count() int {
 
 	return count
 }
+
+func (s *dbStorage) Ping() error {
+	return s.db.Ping()
+}
+
+// instrumentedStorage measures the latency of every operation of the storage it wraps.
+type instrumentedStorage struct {
+	storage storage
+	backend string
+}
+
+func newInstrumentedStorage(backend string, s storage) *instrumentedStorage {
+	return &instrumentedStorage{
+		storage: s,
+		backend: backend,
+	}
+}
+
+// start traces the operation as a child of the span in the context,
+// the returned function ends its span and measures its latency.
+func (s *instrumentedStorage) start(ctx context.Context, operation string, attributes ...attribute.KeyValue) func() {
+	start := time.Now()
+	_, span := startSpan(
+		ctx,
+		"storage."+operation,
+		append(attributes, attribute.String("backend", s.backend))...,
+	)
+
+	return func() {
+		storageDuration.WithLabelValues(s.backend, operation).Observe(time.Since(start).Seconds())
+		span.End()
+	}
+}
+
+func (s *instrumentedStorage) Upload(ctx context.Context, key string, value float64, pulledAt time.Time) {
+	defer s.start(ctx, "upload", attribute.String("key", key))()
+
+	s.storage.Upload(ctx, key, value, pulledAt)
+}
+
+func (s *instrumentedStorage) Get(ctx context.Context, key string) (float64, time.Time, bool) {
+	defer s.start(ctx, "get", attribute.String("key", key))()
+
+	return s.storage.Get(ctx, key)
+}
+
+func (s *instrumentedStorage) GetKeys() map[string]struct{} {
+	defer s.start(context.Background(), "get_keys")()
+
+	return s.storage.GetKeys()
+}
+
+func (s *instrumentedStorage) Count() int {
+	defer s.start(context.Background(), "count")()
+
+	return s.storage.Count()
+}
+
+func (s *instrumentedStorage) Ping() error {
+	defer s.start(context.Background(), "ping")()
+
+	return s.storage.Ping()
+}
@@ -1,20 +1,23 @@
 package main
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestMapStorage_Simple(t *testing.T) {
 	s := newMapStorage()
 	require.Equal(t, 0, s.Count())
 
-	_, exists := s.Get("football")
+	_, _, exists := s.Get(context.Background(), "football")
 	require.False(t, exists)
 
-	s.Upload("football", 0.1)
-	line, exists := s.Get("football")
+	s.Upload(context.Background(), "football", 0.1, time.Now())
+	line, _, exists := s.Get(context.Background(), "football")
 	require.True(t, exists)
 	require.Equal(t, 0.1, line)
 	require.Equal(t, 1, s.Count())
@@ -22,25 +25,25 @@ func TestMapStorage_Simple(t *testing.T) {
 
 func TestMapStorage_Update(t *testing.T) {
 	s := newMapStorage()
-	s.Upload("football", 0.1)
+	s.Upload(context.Background(), "football", 0.1, time.Now())
 	require.Equal(t, 1, s.Count())
 
-	s.Upload("football", 0.2)
+	s.Upload(context.Background(), "football", 0.2, time.Now())
 	require.Equal(t, 1, s.Count())
-	line, _ := s.Get("football")
+	line, _, _ := s.Get(context.Background(), "football")
 	require.Equal(t, 0.2, line)
 }
 
 func TestMapStorage_Count(t *testing.T) {
 	s := newMapStorage()
 
-	s.Upload("football", 0.1)
+	s.Upload(context.Background(), "football", 0.1, time.Now())
 	require.Equal(t, 1, s.Count())
 
-	s.Upload("baseball", 0.1)
+	s.Upload(context.Background(), "baseball", 0.1, time.Now())
 	require.Equal(t, 2, s.Count())
 
-	s.Upload("soccer", 0.1)
+	s.Upload(context.Background(), "soccer", 0.1, time.Now())
 	require.Equal(t, 3, s.Count())
 }
 
@@ -53,19 +56,19 @@ func TestMapStorage_GetKeys(t *testing.T) {
 	key := "football"
 	expected[key] = struct{}{}
 
-	s.Upload(key, 0.1)
+	s.Upload(context.Background(), key, 0.1, time.Now())
 	require.Equal(t, expected, s.GetKeys())
 
 	key = "baseball"
 	expected[key] = struct{}{}
 
-	s.Upload(key, 0.1)
+	s.Upload(context.Background(), key, 0.1, time.Now())
 	require.Equal(t, expected, s.GetKeys())
 
 	key = "soccer"
 	expected[key] = struct{}{}
 
-	s.Upload(key, 0.1)
+	s.Upload(context.Background(), key, 0.1, time.Now())
 	require.Equal(t, expected, s.GetKeys())
 }
 
@@ -73,11 +76,11 @@ func TestDBStorage_Simple(t *testing.T) {
 	s := newDBStorage()
 	require.Equal(t, 0, s.Count())
 
-	_, exists := s.Get("football")
+	_, _, exists := s.Get(context.Background(), "football")
 	require.False(t, exists)
 
-	s.Upload("football", 0.1)
-	line, exists := s.Get("football")
+	s.Upload(context.Background(), "football", 0.1, time.Now())
+	line, _, exists := s.Get(context.Background(), "football")
 	require.True(t, exists)
 	require.Equal(t, 0.1, line)
 	require.Equal(t, 1, s.Count())
@@ -85,25 +88,50 @@ func TestDBStorage_Simple(t *testing.T) {
 
 func TestDBStorage_Update(t *testing.T) {
 	s := newDBStorage()
-	s.Upload("football", 0.1)
+	s.Upload(context.Background(), "football", 0.1, time.Now())
 	require.Equal(t, 1, s.Count())
 
-	s.Upload("football", 0.2)
+	s.Upload(context.Background(), "football", 0.2, time.Now())
 	require.Equal(t, 1, s.Count())
-	line, _ := s.Get("football")
+	line, _, _ := s.Get(context.Background(), "football")
 	require.Equal(t, 0.2, line)
 }
 
+func TestDBStorage_CanceledContext(t *testing.T) {
+	s := newDBStorage()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	cancelFunc()
+
+	s.Upload(ctx, "football", 0.1, time.Now())
+	line, _, exists := s.Get(ctx, "football")
+	require.True(t, exists)
+	require.Equal(t, 0.1, line)
+}
+
+func TestWithoutCancel(t *testing.T) {
+	recordSpans(t)
+
+	ctx, span := startSpan(context.Background(), "storage.get")
+	defer span.End()
+
+	ctx, cancelFunc := context.WithCancel(ctx)
+	cancelFunc()
+
+	detached := withoutCancel(ctx)
+	require.NoError(t, detached.Err())
+	require.Equal(t, span.SpanContext(), trace.SpanContextFromContext(detached))
+}
+
 func TestDBStorage_Count(t *testing.T) {
 	s := newDBStorage()
 
-	s.Upload("football", 0.1)
+	s.Upload(context.Background(), "football", 0.1, time.Now())
 	require.Equal(t, 1, s.Count())
 
-	s.Upload("baseball", 0.1)
+	s.Upload(context.Background(), "baseball", 0.1, time.Now())
 	require.Equal(t, 2, s.Count())
 
-	s.Upload("soccer", 0.1)
+	s.Upload(context.Background(), "soccer", 0.1, time.Now())
 	require.Equal(t, 3, s.Count())
 }
 
@@ -116,18 +144,18 @@ func TestDBStorage_GetKeys(t *testing.T) {
 	key := "football"
 	expected[key] = struct{}{}
 
-	s.Upload(key, 0.1)
+	s.Upload(context.Background(), key, 0.1, time.Now())
 	require.Equal(t, expected, s.GetKeys())
 
 	key = "baseball"
 	expected[key] = struct{}{}
 
-	s.Upload(key, 0.1)
+	s.Upload(context.Background(), key, 0.1, time.Now())
 	require.Equal(t, expected, s.GetKeys())
 
 	key = "soccer"
 	expected[key] = struct{}{}
 
-	s.Upload(key, 0.1)
+	s.Upload(context.Background(), key, 0.1, time.Now())
 	require.Equal(t, expected, s.GetKeys())
 }
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultHeartbeatInterval = 15 * time.Second
+	defaultIdleTimeout       = 30 * time.Second
+)
+
+var (
+	heartbeatResponse = newEncodedResponse(&SportLinesResponse{Kind: SportLinesResponse_HEARTBEAT})
+	reconnectResponse = newEncodedResponse(&SportLinesResponse{Kind: SportLinesResponse_RECONNECT})
+)
+
+// transport is the protocol a stream is served over, it's the class of the delivery latency.
+type transport string
+
+const (
+	grpcTransport      transport = "grpc"
+	grpcWebTransport   transport = "grpc-web"
+	sseTransport       transport = "sse"
+	webSocketTransport transport = "websocket"
+)
+
+type transportKey struct{}
+
+func withTransport(ctx context.Context, t transport) context.Context {
+	return context.WithValue(ctx, transportKey{}, t)
+}
+
+// transportFromContext returns gRPC unless another transport was set.
+func transportFromContext(ctx context.Context) transport {
+	t, exists := ctx.Value(transportKey{}).(transport)
+	if !exists {
+		return grpcTransport
+	}
+
+	return t
+}
+
+type streamState int
+
+const (
+	awaitingSubscription streamState = iota
+	subscribed
+)
+
+// streamGroup is one of the named subscriptions multiplexed on a stream.
+type streamGroup struct {
+	state        streamState
+	subscription subscription
+	sub          *subscriber
+}
+
+// subscriptionStream is the state of one SubscribeOnSportLines call.
+// Every goroutine it starts either finishes in close or exits right after the handler returns.
+type subscriptionStream struct {
+	server      sportLinesPublisherServer
+	srv         SportLinesService_SubscribeOnSportLinesServer
+	client      string
+	requests    *tokenBucket
+	transport   transport
+	ctx         context.Context
+	cancelFunc  context.CancelFunc
+	queue       *outboundQueue
+	groups      map[string]*streamGroup
+	reqChan     chan *SportLinesRequest
+	recvErrChan chan error
+	sendErrChan chan error
+	// closed by run when the stream reaches its maximum age
+	reconnecting chan struct{}
+	// closed by sender once the client was asked to reconnect
+	reconnected chan struct{}
+	wg          *sync.WaitGroup
+}
+
+func newSubscriptionStream(
+	server sportLinesPublisherServer,
+	srv SportLinesService_SubscribeOnSportLinesServer,
+	client string,
+) *subscriptionStream {
+	ctx, cancelFunc := context.WithCancel(srv.Context())
+
+	return &subscriptionStream{
+		server:       server,
+		srv:          srv,
+		client:       client,
+		requests:     server.limiter.newRequestBucket(),
+		transport:    transportFromContext(srv.Context()),
+		ctx:          ctx,
+		cancelFunc:   cancelFunc,
+		queue:        newOutboundQueue(server.config.queueSize, server.config.overflowPolicy),
+		groups:       make(map[string]*streamGroup),
+		reqChan:      make(chan *SportLinesRequest),
+		recvErrChan:  make(chan error, 1),
+		sendErrChan:  make(chan error, 1),
+		reconnecting: make(chan struct{}),
+		reconnected:  make(chan struct{}),
+		wg:           &sync.WaitGroup{},
+	}
+}
+
+func (s *subscriptionStream) run() error {
+	s.wg.Add(1)
+
+	go s.sender()
+	// receiver isn't waited for, Recv returns only after the handler does
+	go s.receiver()
+
+	var idleTimeout, maxAge <-chan time.Time
+
+	if s.server.config.idleTimeout != 0 {
+		timer := time.NewTimer(s.server.config.idleTimeout)
+		defer timer.Stop()
+
+		idleTimeout = timer.C
+	}
+
+	if s.server.config.maxAge != 0 {
+		timer := time.NewTimer(s.server.config.maxAge)
+		defer timer.Stop()
+
+		maxAge = timer.C
+	}
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-s.queue.overflowed:
+			log.Info("client is too slow, closing the stream")
+
+			return slowConsumerError
+		case err := <-s.sendErrChan:
+			log.Info("error in gRPC Send function: ", err)
+
+			return err
+		case err := <-s.recvErrChan:
+			if err == io.EOF {
+				log.Info("connection with client closed due to EOF")
+
+				return nil
+			}
+
+			log.Errorf("error in gRPC Recv function: %v", err)
+
+			return err
+		case <-idleTimeout:
+			log.Info("client sent no request within the idle timeout, closing the stream")
+
+			return idleStreamError
+		case <-maxAge:
+			log.Info("stream reached its maximum age, asking client to reconnect")
+
+			return s.reconnect()
+		case req := <-s.reqChan:
+			idleTimeout = nil
+
+			err := s.handleRequest(req)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handleRequest applies the request to its group and counts it in the usage of the client.
+// Rejected requests are reported in-band while the stream has an active group and close the stream otherwise.
+func (s *subscriptionStream) handleRequest(req *SportLinesRequest) error {
+	err := s.applyRequest(req)
+	s.server.limiter.recordRequest(s.client, err)
+
+	if err != nil {
+		validationFailures.WithLabelValues(validationReason(err)).Inc()
+	}
+
+	if err != nil && len(s.groups) != 0 {
+		log.Infof("rejected subscription update: %v", err)
+		s.queue.push(&outboundResponse{
+			resp:    newErrorResponse(err),
+			groupID: req.GroupId,
+		})
+
+		return nil
+	}
+
+	return err
+}
+
+// applyRequest returns the reason the request was rejected for.
+func (s *subscriptionStream) applyRequest(req *SportLinesRequest) error {
+	if !s.requests.take(time.Now()) {
+		return requestRateError
+	}
+
+	group, exists := s.groups[req.GroupId]
+
+	switch {
+	case req.Action == SportLinesRequest_CLOSE && exists:
+		s.server.hub.unsubscribe(group.sub)
+		delete(s.groups, req.GroupId)
+
+		return nil
+	case req.Action == SportLinesRequest_RESYNC && exists:
+		s.server.hub.resync(group.sub)
+
+		return nil
+	case req.Action == SportLinesRequest_CLOSE, req.Action == SportLinesRequest_RESYNC:
+		return withFieldViolation(unknownGroupError, "groupId", fmt.Sprintf("group %q doesn't exist", req.GroupId))
+	case !exists:
+		group = &streamGroup{
+			state:        awaitingSubscription,
+			subscription: subscription{},
+			sub:          newSubscriber(s.ctx, s.queue, req.GroupId),
+		}
+	}
+
+	subscription, err := s.server.validateRequest(s.ctx, req, group.subscription)
+	if err != nil {
+		return err
+	}
+
+	if p := principalFromContext(s.ctx); p != nil {
+		err = p.authorize(subscription)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.server.limiter.checkSubscription(subscription, s.otherSubscriptions(req.GroupId))
+	if err != nil {
+		return err
+	}
+
+	resumeFrom := uint64(0)
+	if group.state == awaitingSubscription {
+		resumeFrom = req.ResumeFrom
+	}
+
+	s.server.hub.subscribe(group.sub, subscription, resumeFrom, req.Action != SportLinesRequest_REPLACE)
+	group.subscription = subscription
+	group.state = subscribed
+	s.groups[req.GroupId] = group
+
+	return nil
+}
+
+// otherSubscriptions returns the subscriptions of every group of the stream except the given one.
+func (s *subscriptionStream) otherSubscriptions(groupID string) []subscription {
+	subscriptions := make([]subscription, 0, len(s.groups))
+
+	for id, group := range s.groups {
+		if id != groupID {
+			subscriptions = append(subscriptions, group.subscription)
+		}
+	}
+
+	return subscriptions
+}
+
+// reconnect leaves the hub and waits for the sender to flush the queue and ask the client to reconnect.
+func (s *subscriptionStream) reconnect() error {
+	for groupID, group := range s.groups {
+		s.server.hub.unsubscribe(group.sub)
+		delete(s.groups, groupID)
+	}
+
+	close(s.reconnecting)
+
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case err := <-s.sendErrChan:
+		log.Info("error in gRPC Send function: ", err)
+
+		return err
+	case <-s.reconnected:
+		return nil
+	}
+}
+
+// close leaves the hub and waits for the sender, so nothing is sent after the handler returns.
+func (s *subscriptionStream) close() {
+	for _, group := range s.groups {
+		s.server.hub.unsubscribe(group.sub)
+	}
+
+	s.queue.close()
+	s.cancelFunc()
+	s.wg.Wait()
+}
+
+// newErrorResponse reports a rejected request in-band, so that the stream keeps the previous subscription.
+func newErrorResponse(err error) *encodedResponse {
+	return newEncodedResponse(&SportLinesResponse{
+		SportNameToLine: nil,
+		Sequence:        0,
+		Kind:            SportLinesResponse_ERROR,
+		Error:           status.Convert(err).Proto(),
+	})
+}
+
+// sender sends the queued responses and a heartbeat if nothing else was sent during the heartbeat interval.
+func (s *subscriptionStream) sender() {
+	defer s.wg.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			logPanic("sender", r)
+			s.reportError(s.sendErrChan, internalError)
+		}
+	}()
+
+	var heartbeats <-chan time.Time
+
+	if s.server.config.heartbeatInterval != 0 {
+		ticker := time.NewTicker(s.server.config.heartbeatInterval)
+		defer ticker.Stop()
+
+		heartbeats = ticker.C
+	}
+
+	hasSent := false
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.queue.ready:
+			if !s.flush() {
+				return
+			}
+
+			hasSent = true
+		case <-heartbeats:
+			if !hasSent && !s.send(heartbeatResponse) {
+				return
+			}
+
+			hasSent = false
+		case <-s.reconnecting:
+			if s.flush() && s.send(reconnectResponse) {
+				close(s.reconnected)
+			}
+
+			return
+		}
+	}
+}
+
+// flush sends every queued response and reports whether it succeeded.
+func (s *subscriptionStream) flush() bool {
+	for {
+		resp, exists := s.queue.pop()
+		if !exists {
+			return true
+		}
+
+		if !s.send(resp) {
+			return false
+		}
+	}
+}
+
+// send reports whether the response was sent, otherwise the error is passed to run.
+func (s *subscriptionStream) send(resp interface{}) bool {
+	_, span := startLinkedSpan(
+		s.ctx,
+		"stream.send",
+		responseTick(resp),
+		attribute.String("kind", responseKind(resp).String()),
+		attribute.String("transport", string(s.transport)),
+	)
+	err := s.srv.SendMsg(resp)
+	endSpan(span, err)
+
+	if err != nil {
+		sendErrors.Inc()
+		s.sendErrChan <- err
+
+		return false
+	}
+
+	messagesSent.WithLabelValues(responseKind(resp).String()).Inc()
+	s.observeDeliveryLatency(resp)
+
+	return true
+}
+
+// observeDeliveryLatency measures how long ago the lines of a sent response were pulled.
+func (s *subscriptionStream) observeDeliveryLatency(resp interface{}) {
+	now := time.Now()
+
+	for sportName, pulledAt := range sharedResponse(resp).SportNameToPullTime {
+		deliveryLatency.WithLabelValues(sportName, string(s.transport)).Observe(now.Sub(pulledAt.AsTime()).Seconds())
+	}
+}
+
+func (s *subscriptionStream) receiver() {
+	defer func() {
+		if r := recover(); r != nil {
+			logPanic("receiver", r)
+			s.reportError(s.recvErrChan, internalError)
+		}
+	}()
+
+	for {
+		req, err := s.srv.Recv()
+		if err != nil {
+			s.recvErrChan <- err
+
+			return
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case s.reqChan <- req:
+		}
+	}
+}
+
+// reportError passes the error to run unless an error of the goroutine is already waiting there.
+func (s *subscriptionStream) reportError(errChan chan<- error, err error) {
+	select {
+	case errChan <- err:
+	default:
+	}
+}
+
+// singleRequestStream is a stream of a client which sends its only request when the stream starts.
+// The request is received once, after that receiving waits for the client to go away.
+type singleRequestStream struct {
+	grpc.ServerStream
+	// only accessed by the receiver
+	req *SportLinesRequest
+}
+
+func newSingleRequestStream(stream grpc.ServerStream, req *SportLinesRequest) *singleRequestStream {
+	return &singleRequestStream{
+		ServerStream: stream,
+		req:          req,
+	}
+}
+
+func (s *singleRequestStream) Send(resp *SportLinesResponse) error {
+	return s.SendMsg(resp)
+}
+
+func (s *singleRequestStream) Recv() (*SportLinesRequest, error) {
+	if req := s.req; req != nil {
+		s.req = nil
+
+		return req, nil
+	}
+
+	<-s.Context().Done()
+
+	return nil, s.Context().Err()
+}
+
+func (s *singleRequestStream) RecvMsg(m interface{}) error {
+	req, err := s.Recv()
+	if err != nil {
+		return err
+	}
+
+	proto.Merge(m.(proto.Message), req)
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func requireGoroutinesReturnTo(t *testing.T, baseline int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	require.LessOrEqual(t, runtime.NumGoroutine(), baseline)
+}
+
+func TestSubscriptionStream_NoLeaks(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	serverAddr := initServer(t, storage, nil)
+
+	baseline := runtime.NumGoroutine()
+
+	conn, err := grpc.Dial(serverAddr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal("can't dial to server, err:", err)
+	}
+
+	client := NewSportLinesServiceClient(conn)
+	validReq := &SportLinesRequest{
+		SportNames:   []string{soccerSport},
+		TimeInterval: 1,
+	}
+	invalidReq := &SportLinesRequest{
+		SportNames:   []string{"tennis"},
+		TimeInterval: 1,
+	}
+
+	for i := 0; i != 10; i++ {
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		stream, err := client.SubscribeOnSportLines(ctx)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(validReq))
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		cancelFunc()
+
+		stream, err = client.SubscribeOnSportLines(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(validReq))
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		require.NoError(t, stream.CloseSend())
+
+		stream, err = client.SubscribeOnSportLines(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(invalidReq))
+		_, err = stream.Recv()
+		require.Error(t, err)
+	}
+
+	require.NoError(t, conn.Close())
+	requireGoroutinesReturnTo(t, baseline)
+}
+
+func TestSubscriptionStream_StopsGroupTicker(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	server := newSportLinesPublisherServer(
+		storage,
+		nil,
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	)
+	serverAddr := initServerWith(t, server)
+	stream := initClient(t, serverAddr)
+
+	for _, interval := range []int32{1, 2, 3} {
+		err := stream.Send(&SportLinesRequest{
+			SportNames:   []string{soccerSport},
+			TimeInterval: interval,
+		})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.NoError(t, err)
+	}
+
+	server.hub.Lock()
+	require.Equal(t, 1, len(server.hub.groups))
+	server.hub.Unlock()
+
+	require.NoError(t, stream.CloseSend())
+
+	deadline := time.Now().Add(5 * time.Second)
+	groupCount := 1
+
+	for groupCount != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		server.hub.Lock()
+		groupCount = len(server.hub.groups)
+		server.hub.Unlock()
+	}
+
+	require.Equal(t, 0, groupCount)
+}
+
+func initServerWithConfig(t *testing.T, storage *mapStorage, config streamConfig) string {
+	return initServerWith(t, newSportLinesPublisherServer(
+		storage,
+		nil,
+		newLineChangeLog(defaultReplayBufferSize),
+		config,
+	))
+}
+
+func TestSubscriptionStream_Heartbeat(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	config := defaultStreamConfig()
+	config.heartbeatInterval = 100 * time.Millisecond
+	stream := initClient(t, initServerWithConfig(t, storage, config))
+
+	err := stream.Send(&SportLinesRequest{
+		SportNames:   []string{soccerSport},
+		TimeInterval: 1,
+		DeliveryMode: DeliveryMode_CHANGED_ONLY,
+	})
+	require.NoError(t, err)
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, SportLinesResponse_SNAPSHOT, resp.Kind)
+
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, SportLinesResponse_HEARTBEAT, resp.Kind)
+	require.Empty(t, resp.SportNameToLine)
+}
+
+func TestSubscriptionStream_IdleTimeout(t *testing.T) {
+	config := defaultStreamConfig()
+	config.idleTimeout = 100 * time.Millisecond
+	stream := initClient(t, initServerWithConfig(t, newMapStorage(), config))
+
+	_, err := stream.Recv()
+	require.Equal(t, idleStreamError.Error(), err.Error())
+}
+
+func TestSubscriptionStream_MaxAge(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	config := defaultStreamConfig()
+	config.maxAge = 500 * time.Millisecond
+	stream := initClient(t, initServerWithConfig(t, storage, config))
+
+	err := stream.Send(&SportLinesRequest{
+		SportNames:   []string{soccerSport},
+		TimeInterval: 1,
+	})
+	require.NoError(t, err)
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, SportLinesResponse_SNAPSHOT, resp.Kind)
+
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, SportLinesResponse_RECONNECT, resp.Kind)
+
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+}
+
+// panickingStream breaks on the first response, like a bug in the sender would.
+type panickingStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *panickingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *panickingStream) SendMsg(interface{}) error {
+	panic("sender is broken")
+}
+
+func TestSubscriptionStream_SenderPanic(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	server := newSportLinesPublisherServer(
+		storage,
+		nil,
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	)
+
+	srv := newSingleRequestStream(
+		&panickingStream{ServerStream: nil, ctx: context.Background()},
+		&SportLinesRequest{SportNames: []string{soccerSport}, TimeInterval: 1},
+	)
+
+	err := server.SubscribeOnSportLines(srv)
+	require.Equal(t, codes.Internal, status.Code(err))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// certificateReloader serves the certificate, key and client CA from disk and reloads them
+// once they change, so that new connections get new certificates and existing ones keep going.
+type certificateReloader struct {
+	sync.Mutex
+	certPath     string
+	keyPath      string
+	clientCAPath string
+	cert         *tls.Certificate
+	clientCAs    *x509.CertPool
+	modTimes     []time.Time
+}
+
+// newCertificateReloader loads the files for the first time, an empty client CA path disables client certificates.
+func newCertificateReloader(certPath, keyPath, clientCAPath string) (*certificateReloader, error) {
+	r := &certificateReloader{
+		Mutex:        sync.Mutex{},
+		certPath:     certPath,
+		keyPath:      keyPath,
+		clientCAPath: clientCAPath,
+		cert:         nil,
+		clientCAs:    nil,
+		modTimes:     nil,
+	}
+
+	err := r.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *certificateReloader) paths() []string {
+	paths := []string{r.certPath, r.keyPath}
+	if r.clientCAPath != "" {
+		paths = append(paths, r.clientCAPath)
+	}
+
+	return paths
+}
+
+// reload loads the files if any of them was modified since they were loaded last time.
+// The caller must not hold the lock.
+func (r *certificateReloader) reload() error {
+	modTimes := make([]time.Time, 0, 3)
+
+	for _, path := range r.paths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		modTimes = append(modTimes, info.ModTime())
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if sameModTimes(modTimes, r.modTimes) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+
+	if r.clientCAPath != "" {
+		clientCAs, err = loadCertPool(r.clientCAPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.cert != nil {
+		log.Info("reloaded TLS certificates")
+	}
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+
+	return nil
+}
+
+// loadCertPool reads PEM certificates.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in %s", path)
+	}
+
+	return pool, nil
+}
+
+func sameModTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// serverConfig returns a config which checks the files on every handshake.
+// A certificate which can't be reloaded is reported and the previous one is served.
+func (r *certificateReloader) serverConfig(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			err := r.reload()
+			if err != nil {
+				log.Error("can't reload TLS certificates: ", err)
+			}
+
+			r.Lock()
+			defer r.Unlock()
+
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   nextProtos,
+				Certificates: []tls.Certificate{*r.cert},
+			}
+
+			// clients without a certificate are let through, so that tokens, browsers and probes keep working,
+			// the authenticators reject calls which have neither
+			if r.clientCAs != nil {
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+				config.ClientCAs = r.clientCAs
+			}
+
+			return config, nil
+		},
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCertificate issues a certificate signed by the parent, a nil parent makes a self-signed CA.
+func newTestCertificate(t *testing.T, commonName string, serial int64, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	parentCert, parentKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCertificate) keyPair(t *testing.T) tls.Certificate {
+	pair, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	require.NoError(t, err)
+
+	return pair
+}
+
+// writeCertificate writes the files with a modification time in the future,
+// so that a rewrite is noticed even on file systems with coarse timestamps.
+func writeCertificate(t *testing.T, dir string, c *testCertificate, modTime time.Time) (string, string) {
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certPath, c.certPEM, 0o600))
+	require.NoError(t, ioutil.WriteFile(keyPath, c.keyPEM, 0o600))
+	require.NoError(t, os.Chtimes(certPath, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyPath, modTime, modTime))
+
+	return certPath, keyPath
+}
+
+func TestCertificateReloader_Reload(t *testing.T) {
+	ca := newTestCertificate(t, "ca", 1, nil)
+	dir := t.TempDir()
+	certPath, keyPath := writeCertificate(t, dir, newTestCertificate(t, "localhost", 2, ca), time.Now())
+
+	reloader, err := newCertificateReloader(certPath, keyPath, "")
+	require.NoError(t, err)
+
+	listener, err := tls.Listen("tcp", "localhost:0", reloader.serverConfig())
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	serial := func() int64 {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{RootCAs: roots, ServerName: "localhost"})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	require.Equal(t, int64(2), serial())
+
+	writeCertificate(t, dir, newTestCertificate(t, "localhost", 3, ca), time.Now().Add(time.Hour))
+	require.Equal(t, int64(3), serial())
+
+	require.NoError(t, ioutil.WriteFile(certPath, []byte("broken"), 0o600))
+	require.Equal(t, int64(3), serial())
+}
+
+func TestGRPCServer_MutualTLS(t *testing.T) {
+	ca := newTestCertificate(t, "ca", 1, nil)
+	dir := t.TempDir()
+	certPath, keyPath := writeCertificate(t, dir, newTestCertificate(t, "localhost", 2, ca), time.Now())
+	caPath := filepath.Join(dir, "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caPath, ca.certPEM, 0o600))
+
+	reloader, err := newCertificateReloader(certPath, keyPath, caPath)
+	require.NoError(t, err)
+
+	keys, err := loadStaticKeys(writeTestFile(t, "keys.json", `[
+		{"subject": "CN=alice", "principal": "alice", "sportNames": ["soccer"]},
+		{"token": "bob-token", "principal": "bob", "sportNames": ["soccer"]}
+	]`))
+	require.NoError(t, err)
+
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	storage.Upload(context.Background(), footballSport, 0.5, time.Now())
+
+	options := append(
+		authOptions(authenticators{keys}),
+		grpc.Creds(credentials.NewTLS(reloader.serverConfig("h2"))),
+	)
+	serverAddr := initServerWith(t, newSportLinesPublisherServer(
+		storage,
+		nil,
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	), options...)
+
+	subscribe := func(credentialsOption grpc.DialOption, token, sportName string) error {
+		conn, err := grpc.Dial(serverAddr, credentialsOption)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+
+		stream, err := NewSportLinesServiceClient(conn).SubscribeOnSportLines(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = stream.Send(&SportLinesRequest{SportNames: []string{sportName}, TimeInterval: 1})
+		if err != nil {
+			return err
+		}
+
+		_, err = stream.Recv()
+
+		return err
+	}
+
+	withCertificate := func(clientCert *testCertificate) grpc.DialOption {
+		clientDir := t.TempDir()
+		clientCertPath, clientKeyPath := "", ""
+
+		if clientCert != nil {
+			clientCertPath, clientKeyPath = writeCertificate(t, clientDir, clientCert, time.Now())
+		}
+
+		option, err := cliTransportCredentials(caPath, clientCertPath, clientKeyPath)
+		require.NoError(t, err)
+
+		return option
+	}
+
+	require.NoError(t, subscribe(withCertificate(newTestCertificate(t, "alice", 3, ca)), "", soccerSport))
+	require.Equal(
+		t,
+		codes.PermissionDenied,
+		status.Code(subscribe(withCertificate(newTestCertificate(t, "alice", 4, ca)), "", footballSport)),
+	)
+	require.Equal(
+		t,
+		codes.Unauthenticated,
+		status.Code(subscribe(withCertificate(newTestCertificate(t, "bob", 5, ca)), "", soccerSport)),
+	)
+
+	// a client certificate is optional, calls without one need a token
+	require.NoError(t, subscribe(withCertificate(nil), "bob-token", soccerSport))
+	require.Equal(t, codes.Unauthenticated, status.Code(subscribe(withCertificate(nil), "", soccerSport)))
+
+	// a certificate which isn't signed by the client CA fails the handshake
+	require.Error(t, subscribe(withCertificate(newTestCertificate(t, "alice", 6, nil)), "", soccerSport))
+}
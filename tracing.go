@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	tracerName  = "github.com/denistarasov/sport-line-processor"
+	serviceName = "sport-line-processor"
+)
+
+const (
+	otlpGRPC = "grpc"
+	otlpHTTP = "http/protobuf"
+)
+
+// tracingConfig says where spans are exported, tracing is off if neither is set.
+type tracingConfig struct {
+	// otlpEndpoint is the URL of an OTLP collector, http:// means that the connection isn't encrypted
+	otlpEndpoint string
+	// otlpProtocol is grpc or http/protobuf, as in OTEL_EXPORTER_OTLP_PROTOCOL
+	otlpProtocol string
+	// output is stdout or a file path for debugging, spans are written there as JSON lines of the stdouttrace exporter
+	output string
+}
+
+func (c tracingConfig) isEnabled() bool {
+	return c.otlpEndpoint != "" || c.output != ""
+}
+
+// initTracing exports spans to the OTLP collector and to the debug output of the config.
+// Until it's called spans aren't recorded and trace context isn't propagated.
+// The returned function flushes the spans which weren't exported yet.
+func initTracing(ctx context.Context, config tracingConfig) (func(context.Context) error, error) {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	}
+
+	if config.otlpEndpoint != "" {
+		exporter, err := newOTLPExporter(ctx, config.otlpEndpoint, config.otlpProtocol)
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	var file *os.File
+
+	if config.output != "" {
+		var w io.Writer = os.Stdout
+
+		if config.output != "stdout" {
+			var err error
+
+			file, err = os.OpenFile(config.output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+			if err != nil {
+				return nil, err
+			}
+
+			w = file
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	tp := sdktrace.NewTracerProvider(options...)
+	installTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if file != nil {
+			_ = file.Close()
+		}
+
+		return err
+	}, nil
+}
+
+// newOTLPExporter sends spans to the collector at the endpoint.
+// Like OTEL_EXPORTER_OTLP_ENDPOINT, the endpoint of the HTTP protocol is a base URL which /v1/traces is added to.
+func newOTLPExporter(ctx context.Context, endpoint, protocol string) (sdktrace.SpanExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("OTLP endpoint %s must be an http:// or https:// URL", endpoint)
+	}
+
+	switch protocol {
+	case otlpGRPC:
+		return otlptracegrpc.New(ctx, otlptracegrpc.WithEndpointURL(endpoint))
+	case otlpHTTP:
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"))
+	}
+
+	return nil, fmt.Errorf("OTLP protocol %q isn't supported, use %s or %s", protocol, otlpGRPC, otlpHTTP)
+}
+
+func installTracerProvider(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+func startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// startLinkedSpan starts a span which is linked to another one, like a send to the tick which made the response.
+// An invalid span context means that there is nothing to link to.
+func startLinkedSpan(
+	ctx context.Context,
+	name string,
+	linked trace.SpanContext,
+	attributes ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	options := []trace.SpanStartOption{trace.WithAttributes(attributes...)}
+	if linked.IsValid() {
+		options = append(options, trace.WithLinks(trace.Link{SpanContext: linked, Attributes: nil}))
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, name, options...)
+}
+
+// endSpan marks the span as failed if there was an error.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// metadataCarrier lets the propagator read trace context from gRPC metadata.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// startServerSpan continues the trace of the client if its metadata has one.
+func startServerSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	if md, exists := metadata.FromIncomingContext(ctx); exists {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer))
+}
+
+// endServerSpan records the final code of the call.
+func endServerSpan(span trace.Span, err error) {
+	span.SetAttributes(attribute.String("rpc.grpc.status_code", status.Code(err).String()))
+	endSpan(span, err)
+}
+
+func tracingUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	ctx, span := startServerSpan(ctx, info.FullMethod)
+
+	resp, err := handler(ctx, req)
+	endServerSpan(span, err)
+
+	return resp, err
+}
+
+func tracingStreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, span := startServerSpan(ss.Context(), info.FullMethod)
+
+	err := handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	endServerSpan(span, err)
+
+	return err
+}
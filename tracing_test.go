@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	traceParent = "00-" + traceID + "-00f067aa0ba902b7-01"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	installTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { installTracerProvider(noop.NewTracerProvider()) })
+
+	return recorder
+}
+
+func endedSpan(recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+
+	return nil
+}
+
+func TestTracing_GRPCMetadata(t *testing.T) {
+	recorder := recordSpans(t)
+	storage := newInstrumentedStorage("map", newMapStorage())
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	serverAddr := initServerWith(t, newSportLinesPublisherServer(
+		storage,
+		nil,
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	))
+
+	ctx, cancelFunc := context.WithCancel(metadata.AppendToOutgoingContext(
+		context.Background(),
+		"traceparent", traceParent,
+	))
+	defer cancelFunc()
+
+	conn, err := grpc.Dial(serverAddr, grpc.WithInsecure())
+	require.NoError(t, err)
+
+	stream, err := NewSportLinesServiceClient(conn).SubscribeOnSportLines(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(&SportLinesRequest{SportNames: []string{soccerSport}, TimeInterval: 1}))
+	_, err = stream.Recv()
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+	cancelFunc()
+
+	var send, server sdktrace.ReadOnlySpan
+
+	// the tick is found through the link of a send, other tests may have hubs which are still ticking
+	var tick trace.SpanID
+
+	require.Eventually(t, func() bool {
+		server = endedSpan(recorder, "/protobuf.SportLinesService/subscribeOnSportLines")
+		if server == nil {
+			return false
+		}
+
+		// sends of other tests' streams may be recorded as well, so only the ones under this call are taken
+		for _, span := range recorder.Ended() {
+			if span.Name() == "stream.send" && span.Parent().SpanID() == server.SpanContext().SpanID() {
+				send = span
+			}
+
+			if send == span && len(span.Links()) != 0 {
+				tick = span.Links()[0].SpanContext.SpanID()
+			}
+		}
+
+		return send != nil && tick.IsValid()
+	}, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, traceID, server.SpanContext().TraceID().String())
+	require.Equal(t, traceID, send.SpanContext().TraceID().String())
+
+	var subscribeGets, tickGets int
+
+	for _, span := range recorder.Ended() {
+		switch {
+		case span.Name() == "storage.get" && span.Parent().SpanID() == server.SpanContext().SpanID():
+			subscribeGets++
+		case span.Name() == "storage.get" && span.Parent().SpanID() == tick:
+			tickGets++
+		}
+	}
+
+	require.NotZero(t, subscribeGets)
+	require.NotZero(t, tickGets)
+}
+
+func TestTracing_StorageUploadUnderPull(t *testing.T) {
+	recorder := recordSpans(t)
+
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"lines": {"SOCCER": "0.5"}}`))
+	}))
+	defer provider.Close()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	lp := &linePuller{
+		Mutex:              sync.Mutex{},
+		linesProviderAddr:  provider.URL + "/",
+		sportNames:         []string{soccerSport},
+		storage:            newInstrumentedStorage("map", newMapStorage()),
+		isLineProviderDown: false,
+		wg:                 wg,
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	go lp.StartLinePullerWorker(ctx, lp.linesProviderAddr, soccerSport, time.NewTicker(10*time.Millisecond))
+
+	var pull, upload sdktrace.ReadOnlySpan
+
+	require.Eventually(t, func() bool {
+		pull = endedSpan(recorder, "linesProvider.pull")
+		upload = endedSpan(recorder, "storage.upload")
+
+		return pull != nil && upload != nil
+	}, time.Second, 10*time.Millisecond)
+
+	cancelFunc()
+	wg.Wait()
+
+	require.Equal(t, pull.SpanContext().TraceID(), upload.SpanContext().TraceID())
+	require.Equal(t, pull.SpanContext().SpanID(), upload.Parent().SpanID())
+}
+
+func TestTracing_LinesProviderCall(t *testing.T) {
+	recordSpans(t)
+
+	headers := make(chan http.Header, 1)
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+		_, _ = w.Write([]byte(`{"lines": {"SOCCER": "0.5"}}`))
+	}))
+	defer provider.Close()
+
+	lp := &linePuller{
+		Mutex:              sync.Mutex{},
+		linesProviderAddr:  provider.URL + "/",
+		sportNames:         []string{soccerSport},
+		storage:            newMapStorage(),
+		isLineProviderDown: false,
+		wg:                 nil,
+	}
+
+	ctx, span := startSpan(context.Background(), "linesProvider.pull")
+	_, err := lp.pull(ctx, lp.linesProviderAddr, soccerSport)
+	span.End()
+	require.NoError(t, err)
+
+	header := <-headers
+	require.Contains(t, header.Get("traceparent"), span.SpanContext().TraceID().String())
+}
+
+func TestInitTracing_File(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "spans.json")
+
+	shutdown, err := initTracing(context.Background(), tracingConfig{otlpEndpoint: "", otlpProtocol: "", output: output})
+	require.NoError(t, err)
+	t.Cleanup(func() { installTracerProvider(noop.NewTracerProvider()) })
+
+	_, span := startSpan(context.Background(), "storage.get")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"Name":"storage.get"`)
+}
+
+func TestInitTracing_OTLPHTTP(t *testing.T) {
+	requests := make(chan *coltracepb.ExportTraceServiceRequest, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, "/v1/traces", r.URL.Path)
+		require.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+		req := &coltracepb.ExportTraceServiceRequest{}
+		require.NoError(t, proto.Unmarshal(body, req))
+		requests <- req
+	}))
+	defer collector.Close()
+
+	shutdown, err := initTracing(context.Background(), tracingConfig{
+		otlpEndpoint: collector.URL,
+		otlpProtocol: otlpHTTP,
+		output:       "",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { installTracerProvider(noop.NewTracerProvider()) })
+
+	_, span := startSpan(context.Background(), "storage.get")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	req := <-requests
+	require.Equal(t, "storage.get", req.ResourceSpans[0].ScopeSpans[0].Spans[0].Name)
+}
+
+// traceCollector receives spans over OTLP gRPC.
+type traceCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	requests chan *coltracepb.ExportTraceServiceRequest
+}
+
+func (c *traceCollector) Export(
+	_ context.Context,
+	req *coltracepb.ExportTraceServiceRequest,
+) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.requests <- req
+
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func TestInitTracing_OTLPGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	collector := &traceCollector{requests: make(chan *coltracepb.ExportTraceServiceRequest, 1)}
+	s := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(s, collector)
+
+	go func() { _ = s.Serve(listener) }()
+	defer s.Stop()
+
+	shutdown, err := initTracing(context.Background(), tracingConfig{
+		otlpEndpoint: "http://" + listener.Addr().String(),
+		otlpProtocol: otlpGRPC,
+		output:       "",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { installTracerProvider(noop.NewTracerProvider()) })
+
+	_, span := startSpan(context.Background(), "stream.send")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	req := <-collector.requests
+	require.Equal(t, "stream.send", req.ResourceSpans[0].ScopeSpans[0].Spans[0].Name)
+}
+
+func TestInitTracing_InvalidOTLPConfig(t *testing.T) {
+	_, err := initTracing(context.Background(), tracingConfig{
+		otlpEndpoint: "collector:4317",
+		otlpProtocol: otlpGRPC,
+		output:       "",
+	})
+	require.Error(t, err)
+
+	_, err = initTracing(context.Background(), tracingConfig{
+		otlpEndpoint: "http://collector:4317",
+		otlpProtocol: "http/json",
+		output:       "",
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	webSocketPath = "/ws"
+	// requests are small, so a bigger message is rejected without reading it
+	maxWebSocketMessageSize = 64 << 10
+	// browsers can't set the authorization header on WebSocket, so the token is offered as the subprotocol after this one
+	bearerSubprotocol = "bearer"
+)
+
+var invalidMessageError gRPCServerError = status.Error(codes.InvalidArgument, "message is not a JSON request")
+
+// webSocketHandler serves SubscribeOnSportLines over WebSocket. Every text message of the client is a request
+// and every message of the server is a response, both in the JSON mapping of the proto messages.
+type webSocketHandler struct {
+	server   sportLinesPublisherServer
+	auth     authenticators
+	upgrader websocket.Upgrader
+}
+
+// newWebSocketHandler accepts connections from the same origin and from the allowed ones, "*" allows any origin.
+func newWebSocketHandler(
+	server sportLinesPublisherServer,
+	auth authenticators,
+	allowedOrigins []string,
+) *webSocketHandler {
+	isAllowed := isAllowedOrigin(allowedOrigins)
+
+	return &webSocketHandler{
+		server: server,
+		auth:   auth,
+		upgrader: websocket.Upgrader{
+			// the token itself is never echoed back
+			Subprotocols: []string{bearerSubprotocol},
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				if origin == "" || isAllowed(origin) {
+					return true
+				}
+
+				u, err := url.Parse(origin)
+
+				return err == nil && strings.EqualFold(u.Host, r.Host)
+			},
+		},
+	}
+}
+
+func (h *webSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p, err := h.auth.authenticateHTTP(r, webSocketToken(r))
+	if err != nil {
+		writeJSONError(w, err)
+
+		return
+	}
+
+	// the upgrader replies with an HTTP error itself
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Info("can't upgrade to WebSocket: ", err)
+
+		return
+	}
+	defer conn.Close()
+
+	conn.SetReadLimit(maxWebSocketMessageSize)
+
+	stream := &webSocketStream{
+		ctx:  httpStreamContext(r, p, webSocketTransport),
+		conn: conn,
+	}
+
+	err = h.server.SubscribeOnSportLines(stream)
+	if err != nil && status.Code(err) != codes.Canceled {
+		// the status can't be sent in a close frame as it may be too long, so the client gets it as the last message
+		_ = stream.SendMsg(newErrorResponse(err))
+	}
+
+	_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
+
+// webSocketToken returns the token offered as new WebSocket(url, ["bearer", token]) does
+// or the one in the access_token query parameter.
+func webSocketToken(r *http.Request) string {
+	protocols := websocket.Subprotocols(r)
+	for i := 0; i+1 < len(protocols); i++ {
+		if protocols[i] == bearerSubprotocol {
+			return protocols[i+1]
+		}
+	}
+
+	return r.URL.Query().Get(accessTokenParameter)
+}
+
+// webSocketStream is SportLinesService_SubscribeOnSportLinesServer over a WebSocket connection.
+// Headers and trailers are never sent. Only the sender writes and only the receiver reads, as the connection requires.
+type webSocketStream struct {
+	ctx  context.Context
+	conn *websocket.Conn
+}
+
+func (s *webSocketStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *webSocketStream) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *webSocketStream) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *webSocketStream) SetTrailer(metadata.MD) {}
+
+func (s *webSocketStream) Send(resp *SportLinesResponse) error {
+	return s.SendMsg(resp)
+}
+
+func (s *webSocketStream) SendMsg(m interface{}) error {
+	resp := responseMessage(m)
+
+	data, err := protojson.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// Recv returns io.EOF once the client closes the connection normally, as a gRPC client does with CloseSend.
+func (s *webSocketStream) Recv() (*SportLinesRequest, error) {
+	messageType, data, err := s.conn.ReadMessage()
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return nil, io.EOF
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if messageType != websocket.TextMessage {
+		return nil, invalidMessageError
+	}
+
+	req := &SportLinesRequest{}
+
+	err = protojson.Unmarshal(data, req)
+	if err != nil {
+		log.Info("can't parse WebSocket request: ", err)
+
+		return nil, invalidMessageError
+	}
+
+	return req, nil
+}
+
+func (s *webSocketStream) RecvMsg(m interface{}) error {
+	req, err := s.Recv()
+	if err != nil {
+		return err
+	}
+
+	proto.Merge(m.(proto.Message), req)
+
+	return nil
+}
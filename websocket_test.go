@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func initWebSocketClient(t *testing.T, storage storage) *websocket.Conn {
+	server := httptest.NewServer(newWebSocketHandler(newSportLinesPublisherServer(
+		storage,
+		nil,
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	), nil, nil))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+webSocketPath, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func readWebSocketResponse(t *testing.T, conn *websocket.Conn) *SportLinesResponse {
+	messageType, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, websocket.TextMessage, messageType)
+
+	resp := &SportLinesResponse{}
+	require.NoError(t, protojson.Unmarshal(data, resp))
+
+	return resp
+}
+
+func TestWebSocketHandler_Subscribe(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	storage.Upload(context.Background(), footballSport, 0.5, time.Now())
+	conn := initWebSocketClient(t, storage)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(
+		`{"sportNames": ["soccer"], "interval": "0.2s", "groupId": "main"}`,
+	)))
+
+	resp := readWebSocketResponse(t, conn)
+	require.Equal(t, SportLinesResponse_SNAPSHOT, resp.Kind)
+	require.Equal(t, "main", resp.GroupId)
+	require.Equal(t, map[string]float64{soccerSport: 0.5}, resp.SportNameToLine)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(
+		`{"sportNames": ["tennis"], "action": "ADD", "groupId": "main"}`,
+	)))
+
+	resp = readWebSocketResponse(t, conn)
+	require.Equal(t, SportLinesResponse_ERROR, resp.Kind)
+	require.Equal(t, int32(codes.InvalidArgument), resp.Error.Code)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(
+		`{"sportNames": ["football"], "action": "ADD", "groupId": "main"}`,
+	)))
+
+	for {
+		resp = readWebSocketResponse(t, conn)
+		if _, exists := resp.SportNameToLine[footballSport]; exists {
+			break
+		}
+	}
+
+	require.Equal(t, []string{footballSport}, resp.AbsoluteSportNames)
+	require.Equal(t, 0.5, resp.SportNameToLine[footballSport])
+}
+
+func TestWebSocketHandler_InvalidMessage(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	conn := initWebSocketClient(t, storage)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"sportNames": "soccer"}`)))
+
+	resp := readWebSocketResponse(t, conn)
+	require.Equal(t, SportLinesResponse_ERROR, resp.Kind)
+	require.Equal(t, "message is not a JSON request", resp.Error.Message)
+
+	_, _, err := conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+}
+
+func TestWebSocketHandler_Authentication(t *testing.T) {
+	storage := newMapStorage()
+	storage.Upload(context.Background(), soccerSport, 0.5, time.Now())
+	storage.Upload(context.Background(), footballSport, 0.5, time.Now())
+
+	keys, err := loadStaticKeys(writeTestFile(t, "keys.json", `[
+		{"token": "alice-token", "principal": "alice", "sportNames": ["soccer"]}
+	]`))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(newWebSocketHandler(newSportLinesPublisherServer(
+		storage,
+		nil,
+		newLineChangeLog(defaultReplayBufferSize),
+		defaultStreamConfig(),
+	), authenticators{keys}, nil))
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + webSocketPath
+
+	for _, protocols := range [][]string{nil, {bearerSubprotocol, "eve-token"}} {
+		dialer := websocket.Dialer{Subprotocols: protocols}
+		_, resp, err := dialer.Dial(url, nil)
+		require.Equal(t, websocket.ErrBadHandshake, err)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	dialer := websocket.Dialer{Subprotocols: []string{bearerSubprotocol, "alice-token"}}
+	conn, _, err := dialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	require.Equal(t, bearerSubprotocol, conn.Subprotocol())
+
+	queryConn, _, err := websocket.DefaultDialer.Dial(url+"?access_token=alice-token", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { queryConn.Close() })
+
+	for _, c := range []*websocket.Conn{conn, queryConn} {
+		require.NoError(t, c.WriteMessage(websocket.TextMessage, []byte(`{"sportNames": ["soccer"], "interval": "1s"}`)))
+		require.Equal(t, SportLinesResponse_SNAPSHOT, readWebSocketResponse(t, c).Kind)
+
+		// the principal of the token applies to the stream
+		require.NoError(t, c.WriteMessage(websocket.TextMessage, []byte(`{"sportNames": ["football"], "action": "ADD"}`)))
+
+		resp := readWebSocketResponse(t, c)
+		require.Equal(t, SportLinesResponse_ERROR, resp.Kind)
+		require.Equal(t, int32(codes.PermissionDenied), resp.Error.Code)
+	}
+}